@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+)
+
+const (
+	yesFlag      = "yes"
+	shortYesFlag = "y"
+	dryRunFlag   = "dry-run"
+	allFlag      = "all"
+
+	errUnfilteredDeleteMsg    = "Refusing to delete every sequence annotation in the collection without a filter. Use --all to override."
+	errFilteredAllMsg         = "--all cannot be combined with a filter."
+	matchedDeleteMsgFmt       = "%d sequence annotation(s) match in: %s\n"
+	dryRunDeleteMsg           = "Dry run, nothing was deleted."
+	confirmDeleteMsgFmt       = "Delete %d sequence annotation(s)? [y/N]: "
+	cancelledDeleteMsg        = "Delete cancelled."
+	deletedMsgFmt             = "Deleted %d sequence annotation(s).\n"
+	errCouldNotDeleteMsgFmt   = "Could not delete the sequence annotations. Error: '%w'"
+	errCouldNotCountDelMsgFmt = "Could not count the sequence annotations to delete. Error: '%w'"
+)
+
+func init() {
+	deleteCmd.Flags().BoolP(yesFlag, shortYesFlag, false, "Deletes without asking for confirmation.")
+	deleteCmd.Flags().Bool(dryRunFlag, false, "Only reports how many sequence annotations would be deleted.")
+	deleteCmd.Flags().Bool(allFlag, false, "Allows deleting every sequence annotation in the collection when no filter is given.")
+	addFilterFlags(deleteCmd)
+	addStoreFlags(deleteCmd)
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes sequence annotation records from the database.",
+	Long: `Deletes sequence annotation records from the database.
+Selects the records with the same filters as the search command, or by their
+unique ids with --id. The number of matching records is reported first, then
+the delete must be confirmed, unless --yes is given. Deleting the whole
+collection requires --all.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := newSeqAnnotationFilter(cmd)
+		if err != nil {
+			return err
+		}
+
+		all, _ := cmd.Flags().GetBool(allFlag)
+		if filter.IsEmpty() && !all {
+			return errors.New(errUnfilteredDeleteMsg)
+		} else if !filter.IsEmpty() && all {
+			return errors.New(errFilteredAllMsg)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		ctx := context.Background()
+		matched, err := st.Count(ctx, filter)
+		if err != nil {
+			return fmt.Errorf(errCouldNotCountDelMsgFmt, err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), matchedDeleteMsgFmt, matched, st.Name())
+		if dryRun, _ := cmd.Flags().GetBool(dryRunFlag); dryRun {
+			fmt.Fprintln(cmd.OutOrStdout(), dryRunDeleteMsg)
+			return nil
+		}
+
+		if matched == 0 {
+			return nil
+		}
+
+		if yes, _ := cmd.Flags().GetBool(yesFlag); !yes && !confirm(cmd.InOrStdin(), cmd.OutOrStdout(), fmt.Sprintf(confirmDeleteMsgFmt, matched)) {
+			fmt.Fprintln(cmd.OutOrStdout(), cancelledDeleteMsg)
+			return nil
+		}
+
+		deleted, err := st.Delete(ctx, filter)
+		if err != nil {
+			return fmt.Errorf(errCouldNotDeleteMsgFmt, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), deletedMsgFmt, deleted)
+		return nil
+	},
+}
+
+// confirm prints the prompt and reads a yes or no answer. Anything other than
+// 'y' or 'yes' is a no.
+func confirm(in io.Reader, out io.Writer, prompt string) bool {
+	fmt.Fprint(out, prompt)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
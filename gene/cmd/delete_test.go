@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// uploadTestStore uploads the test GenomeDiff file to a new local store, returning its filepath.
+func uploadTestStore(t *testing.T) string {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdData)
+	storePath := filepath.Join(dir, "local.db")
+	_, _, err := executeCommand(t, "", "upload", "--local-store", storePath, filePath)
+	assert.Nil(t, err)
+	return storePath
+}
+
+func TestDeleteCmd(t *testing.T) {
+	cases := []struct {
+		name      string
+		stdin     string
+		args      []string
+		expected  []string
+		remaining int64
+	}{
+		{"Dry Run", "", []string{"--mutation-type", "snp", "--dry-run"}, []string{"2 sequence annotation(s) match", dryRunDeleteMsg}, 5},
+		{"Dry Run Without Confirming", "", []string{"--mutation-type", "snp", "--dry-run", "--yes"}, []string{dryRunDeleteMsg}, 5},
+		{"Answered No", "n\n", []string{"--mutation-type", "snp"}, []string{"Delete 2 sequence annotation(s)? [y/N]: ", cancelledDeleteMsg}, 5},
+		{"No Answer", "", []string{"--mutation-type", "snp"}, []string{cancelledDeleteMsg}, 5},
+		{"Answered Yes", "y\n", []string{"--mutation-type", "snp"}, []string{"Deleted 2 sequence annotation(s)."}, 3},
+		{"Yes Flag", "", []string{"--mutation-type", "snp", "--yes"}, []string{"Deleted 2 sequence annotation(s)."}, 3},
+		{"No Matches", "", []string{"--seq-id", "REL607"}, []string{"0 sequence annotation(s) match"}, 5},
+		{"All", "yes\n", []string{"--all"}, []string{"Deleted 5 sequence annotation(s)."}, 0},
+	}
+
+	for _, c := range cases {
+		storePath := uploadTestStore(t)
+		stdout, _, err := executeCommand(t, c.stdin, append([]string{"delete", "--local-store", storePath}, c.args...)...)
+		assert.Nil(t, err, c.name)
+		for _, expected := range c.expected {
+			assert.Contains(t, stdout, expected, c.name)
+		}
+		assert.Equal(t, c.remaining, countStored(t, storePath), c.name)
+	}
+}
+
+func TestDeleteCmdRefused(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Unfiltered", []string{}, errUnfilteredDeleteMsg},
+		{"Unfiltered Dry Run", []string{"--dry-run"}, errUnfilteredDeleteMsg},
+		{"Filtered All", []string{"--all", "--mutation-type", "snp", "--yes"}, errFilteredAllMsg},
+	}
+
+	for _, c := range cases {
+		storePath := uploadTestStore(t)
+		stdout, _, err := executeCommand(t, "y\n", append([]string{"delete", "--local-store", storePath}, c.args...)...)
+		assert.NotNil(t, err, c.name)
+		assert.Equal(t, c.expected, err.Error(), c.name)
+		assert.Equal(t, exitError, commandExitCode(err), c.name)
+		assert.Equal(t, "", stdout, c.name)
+		assert.Equal(t, int64(5), countStored(t, storePath), c.name)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	cases := []struct {
+		answer   string
+		expected bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{" YES \n", true},
+		{"Y", true},
+		{"n\n", false},
+		{"no\n", false},
+		{"yep\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		assert.Equal(t, c.expected, confirm(strings.NewReader(c.answer), out, "Delete? "), c.answer)
+		assert.Equal(t, "Delete? ", out.String(), c.answer)
+	}
+}
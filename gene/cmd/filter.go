@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/store"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+const (
+	idFilterFlag          = "id"
+	seqIdFilterFlag       = "seq-id"
+	minPositionFilterFlag = "min-position"
+	maxPositionFilterFlag = "max-position"
+	geneFilterFlag        = "gene"
+	mutationTypeFlag      = "mutation-type"
+	minFreqFilterFlag     = "min-freq"
+	maxFreqFilterFlag     = "max-freq"
+	generationFilterFlag  = "generation"
+	applicationFilterFlag = "application"
+)
+
+// addFilterFlags adds the flags of the filter language shared by the commands
+// that select sequence annotation records.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice(idFilterFlag, nil, "Only match these unique ids. Can be repeated or comma separated.")
+	cmd.Flags().String(seqIdFilterFlag, "", "Only match this reference sequence id.")
+	cmd.Flags().Int64(minPositionFilterFlag, 0, "Only match positions greater than or equal to this.")
+	cmd.Flags().Int64(maxPositionFilterFlag, 0, "Only match positions less than or equal to this.")
+	cmd.Flags().String(geneFilterFlag, "", "Only match mutations affecting this gene.")
+	cmd.Flags().String(mutationTypeFlag, "", fmt.Sprintf("Only match this type of mutation: %s", strings.Join(store.MutationTypes(), ", ")))
+	cmd.Flags().Float64(minFreqFilterFlag, 0, "Only match frequencies greater than or equal to this, within [0, 1].")
+	cmd.Flags().Float64(maxFreqFilterFlag, 0, "Only match frequencies less than or equal to this, within [0, 1].")
+	cmd.Flags().String(generationFilterFlag, "", "Only match this generation.")
+	cmd.Flags().String(applicationFilterFlag, "", "Only match annotations generated by this application.")
+}
+
+// newSeqAnnotationFilter builds the filter out of the command's filter flags.
+// Returns an error if any of the values are out of range.
+func newSeqAnnotationFilter(cmd *cobra.Command) (*store.Filter, error) {
+	flags := cmd.Flags()
+	filter := &store.Filter{}
+	filter.UniqueIds, _ = flags.GetStringSlice(idFilterFlag)
+	filter.SequenceId, _ = flags.GetString(seqIdFilterFlag)
+	filter.Gene, _ = flags.GetString(geneFilterFlag)
+	filter.Generation, _ = flags.GetString(generationFilterFlag)
+	filter.Application, _ = flags.GetString(applicationFilterFlag)
+	mutationType, _ := flags.GetString(mutationTypeFlag)
+	filter.MutationType = model.MutationType(strings.ToLower(mutationType))
+
+	if flags.Changed(minPositionFilterFlag) {
+		minPosition, _ := flags.GetInt64(minPositionFilterFlag)
+		filter.MinPosition = &minPosition
+	}
+
+	if flags.Changed(maxPositionFilterFlag) {
+		maxPosition, _ := flags.GetInt64(maxPositionFilterFlag)
+		filter.MaxPosition = &maxPosition
+	}
+
+	if flags.Changed(minFreqFilterFlag) {
+		minFreq, _ := flags.GetFloat64(minFreqFilterFlag)
+		filter.MinFrequency = &minFreq
+	}
+
+	if flags.Changed(maxFreqFilterFlag) {
+		maxFreq, _ := flags.GetFloat64(maxFreqFilterFlag)
+		filter.MaxFrequency = &maxFreq
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
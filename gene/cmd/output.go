@@ -0,0 +1,414 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/model/pb"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	outputTypeFlag    = "ot"
+	csvOutputType     = "csv"
+	tsvOutputType     = "tsv"
+	protoOutputType   = "proto"
+	jsonOutputType    = "json"
+	jsonlOutputType   = "jsonl"
+	defaultOutputType = csvOutputType
+	csvDelimiter      = ','
+	tsvDelimiter      = '\t'
+
+	outputFlag       = "output"
+	shortOutputFlag  = "o"
+	outputFileMode   = 0666
+	outputTempSuffix = ".tmp"
+
+	headerFlag  = "header"
+	columnsFlag = "columns"
+
+	// The json output is indented the same as encoding the whole document at once.
+	jsonIndent              = "  "
+	jsonCollectionPrefix    = jsonIndent + jsonIndent
+	jsonSeqAnnotationPrefix = jsonCollectionPrefix + jsonIndent
+	jsonCollectionsStart    = "{\n" + jsonIndent + "\"Collections\": ["
+
+	outputTypeFlagUsage = "Output type: csv, tsv, json, jsonl, proto. Json writes one document holding the collections, and jsonl one sequence annotation per line. Proto writes each sequence annotation as a varint length-delimited protobuf message."
+
+	errUnknownOutputTypeMsgFmt = "Unknown output type: '%s'. Expected one of: csv, tsv, json, jsonl, proto"
+	errUnknownColumnMsgFmt     = "Unknown column: '%s'. Expected one of: %s"
+	errUnknownOutputExtMsgFmt  = "Cannot pick the output type from the extension of: '%s'. Expected one of: .csv, .tsv, .json, .jsonl, .pb, or give the output type with --ot."
+)
+
+var (
+	// seqAnnotationColumns renders each of the csv and tsv columns of a sequence annotation,
+	// given the index of the collection it's from.
+	seqAnnotationColumns = map[string]func(collection int, sa model.SequenceAnnotation) string{
+		"collection":    func(collection int, sa model.SequenceAnnotation) string { return strconv.Itoa(collection) },
+		"unique_id":     func(collection int, sa model.SequenceAnnotation) string { return sa.UniqueId },
+		"sequence_id":   func(collection int, sa model.SequenceAnnotation) string { return sa.SequenceId },
+		"position":      func(collection int, sa model.SequenceAnnotation) string { return sa.Position },
+		"generation":    func(collection int, sa model.SequenceAnnotation) string { return sa.Generation },
+		"population":    func(collection int, sa model.SequenceAnnotation) string { return sa.Population },
+		"replicate":     func(collection int, sa model.SequenceAnnotation) string { return sa.Replicate },
+		"treatment":     func(collection int, sa model.SequenceAnnotation) string { return sa.Treatment },
+		"time_point":    func(collection int, sa model.SequenceAnnotation) string { return sa.TimePoint },
+		"mutation":      func(collection int, sa model.SequenceAnnotation) string { return sa.Mutation },
+		"mutation_type": func(collection int, sa model.SequenceAnnotation) string { return string(sa.Variant.Type) },
+		"frequency":     func(collection int, sa model.SequenceAnnotation) string { return sa.Frequency },
+		"annotation":    func(collection int, sa model.SequenceAnnotation) string { return sa.Annotation },
+		"effect":        func(collection int, sa model.SequenceAnnotation) string { return string(sa.Effect.Category) },
+		"gene":          func(collection int, sa model.SequenceAnnotation) string { return sa.Gene },
+		"description":   func(collection int, sa model.SequenceAnnotation) string { return sa.Description },
+		"application":   func(collection int, sa model.SequenceAnnotation) string { return sa.Application },
+		"app_version":   func(collection int, sa model.SequenceAnnotation) string { return sa.AppVersion },
+		"source":        func(collection int, sa model.SequenceAnnotation) string { return sa.Source },
+		"position_value": func(collection int, sa model.SequenceAnnotation) string {
+			return strconv.FormatInt(sa.PositionValue, 10)
+		},
+		"frequency_value": func(collection int, sa model.SequenceAnnotation) string {
+			return strconv.FormatFloat(sa.FrequencyValue, 'g', -1, 64)
+		},
+	}
+
+	// outputExtTypes picks the output type from the extension of the output filepath.
+	outputExtTypes = map[string]string{
+		".csv":   csvOutputType,
+		".tsv":   tsvOutputType,
+		".json":  jsonOutputType,
+		".jsonl": jsonlOutputType,
+		".pb":    protoOutputType,
+	}
+
+	defaultColumns = []string{"collection", "sequence_id", "position", "mutation", "frequency", "annotation", "gene", "description", "source"}
+)
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String(outputTypeFlag, defaultOutputType, outputTypeFlagUsage)
+	cmd.Flags().StringP(outputFlag, shortOutputFlag, "", "Filepath to write the output to instead of stdout. The extension picks the output type unless --ot is given: .csv, .tsv, .json, .jsonl, .pb")
+	cmd.Flags().Bool(headerFlag, true, "Writes a header row of the column names first, for csv and tsv output.")
+	cmd.Flags().StringSlice(columnsFlag, defaultColumns, fmt.Sprintf("Columns of csv and tsv output, in order. Can be repeated or comma separated. Available: %s", strings.Join(columnNames(), ", ")))
+}
+
+func columnNames() []string {
+	names := make([]string, 0, len(seqAnnotationColumns))
+	for name := range seqAnnotationColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectionSeqAnnotation is a jsonl output line, a sequence annotation along
+// with the index of the collection it's from.
+type collectionSeqAnnotation struct {
+	Collection int
+	model.SequenceAnnotation
+}
+
+// seqAnnotationWriter writes collections of sequence annotations in an output type,
+// one at a time as they're parsed, tagging each with the index of its collection.
+type seqAnnotationWriter interface {
+	// WriteCollection writes the next collection of sequence annotations.
+	WriteCollection(collection []model.SequenceAnnotation) error
+	// Close writes whatever is left of the output, without closing the underlying writer.
+	Close() error
+}
+
+// printSeqAnnotations writes each collection of sequence annotations in the output
+// type given by the command's flags, to stdout or the output file.
+func printSeqAnnotations(cmd *cobra.Command, results [][]model.SequenceAnnotation) error {
+	return streamSeqAnnotations(cmd, func(write func([]model.SequenceAnnotation) error) error {
+		for _, collection := range results {
+			if err := write(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// streamSeqAnnotations writes each collection of sequence annotations the producer
+// passes to its write function as it comes, in the output type given by the command's
+// flags, to stdout or the output file. The output file is left untouched if the
+// producer returns an error.
+func streamSeqAnnotations(cmd *cobra.Command, producer func(write func([]model.SequenceAnnotation) error) error) error {
+	outputType, err := resolveOutputType(cmd)
+	if err != nil {
+		return err
+	}
+
+	output := func(w io.Writer) error {
+		sw, err := newSeqAnnotationWriter(cmd, w, outputType)
+		if err != nil {
+			return err
+		}
+
+		if err = producer(sw.WriteCollection); err != nil {
+			return err
+		}
+		return sw.Close()
+	}
+
+	outputPath, _ := cmd.Flags().GetString(outputFlag)
+	if outputPath == "" {
+		return output(cmd.OutOrStdout())
+	}
+
+	cmdLog.Printf("Writing Output: %s\n", outputPath)
+	return writeFileAtomic(outputPath, output)
+}
+
+// resolveOutputType picks the output type from the output file's extension, unless
+// it's given explicitly.
+func resolveOutputType(cmd *cobra.Command) (string, error) {
+	outputType, _ := cmd.Flags().GetString(outputTypeFlag)
+	outputPath, _ := cmd.Flags().GetString(outputFlag)
+	if outputPath == "" || cmd.Flags().Changed(outputTypeFlag) {
+		return strings.ToLower(outputType), nil
+	}
+
+	if extType, ok := outputExtTypes[strings.ToLower(filepath.Ext(outputPath))]; ok {
+		return extType, nil
+	}
+	return "", fmt.Errorf(errUnknownOutputExtMsgFmt, outputPath)
+}
+
+func newSeqAnnotationWriter(cmd *cobra.Command, w io.Writer, outputType string) (seqAnnotationWriter, error) {
+	switch outputType {
+	case protoOutputType:
+		return &protoSeqAnnotationWriter{bw: bufio.NewWriter(w)}, nil
+	case jsonOutputType:
+		return newJsonSeqAnnotationWriter(w), nil
+	case jsonlOutputType:
+		bw := bufio.NewWriter(w)
+		encoder := json.NewEncoder(bw)
+		encoder.SetEscapeHTML(false)
+		return &jsonlSeqAnnotationWriter{bw: bw, encoder: encoder}, nil
+	case csvOutputType, tsvOutputType:
+		delim := csvDelimiter
+		if outputType == tsvOutputType {
+			delim = tsvDelimiter
+		}
+
+		header, _ := cmd.Flags().GetBool(headerFlag)
+		columns, _ := cmd.Flags().GetStringSlice(columnsFlag)
+		return newDelimitedSeqAnnotationWriter(w, delim, header, columns)
+	}
+	return nil, fmt.Errorf(errUnknownOutputTypeMsgFmt, outputType)
+}
+
+// createTempFile creates a new temporary file next to the filepath. Unlike os.CreateTemp,
+// the file is created with outputFileMode masked by the umask, the same as os.Create.
+func createTempFile(filePath string) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".")
+	for {
+		name := prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + outputTempSuffix
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, outputFileMode)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+// writeFileAtomic writes to a temporary file next to the filepath, then renames it
+// over the filepath, so the file is never left partially written. The temporary
+// file is removed if writing fails. A file being replaced keeps its mode, while a
+// new file gets outputFileMode masked by the umask.
+func writeFileAtomic(filePath string, write func(w io.Writer) error) error {
+	existing, statErr := os.Stat(filePath)
+	tmp, err := createTempFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmp.Name()
+	bw := bufio.NewWriter(tmp)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && statErr == nil {
+		err = os.Chmod(tmpPath, existing.Mode().Perm())
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// delimitedSeqAnnotationWriter writes a row of the columns for each sequence annotation,
+// quoting fields as needed per RFC 4180, and preceded by a header row if asked for.
+type delimitedSeqAnnotationWriter struct {
+	csvWriter  *csv.Writer
+	renderers  []func(int, model.SequenceAnnotation) string
+	row        []string
+	collection int
+}
+
+func newDelimitedSeqAnnotationWriter(w io.Writer, delim rune, header bool, columns []string) (*delimitedSeqAnnotationWriter, error) {
+	names := make([]string, len(columns))
+	renderers := make([]func(int, model.SequenceAnnotation) string, len(columns))
+	for i, column := range columns {
+		names[i] = strings.ToLower(strings.TrimSpace(column))
+		renderer, ok := seqAnnotationColumns[names[i]]
+		if !ok {
+			return nil, fmt.Errorf(errUnknownColumnMsgFmt, column, strings.Join(columnNames(), ", "))
+		}
+		renderers[i] = renderer
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = delim
+	if header {
+		if err := csvWriter.Write(names); err != nil {
+			return nil, err
+		}
+	}
+	return &delimitedSeqAnnotationWriter{csvWriter: csvWriter, renderers: renderers, row: make([]string, len(renderers))}, nil
+}
+
+func (dw *delimitedSeqAnnotationWriter) WriteCollection(collection []model.SequenceAnnotation) error {
+	for _, sa := range collection {
+		for j, renderer := range dw.renderers {
+			dw.row[j] = renderer(dw.collection, sa)
+		}
+
+		if err := dw.csvWriter.Write(dw.row); err != nil {
+			return err
+		}
+	}
+
+	dw.collection++
+	dw.csvWriter.Flush()
+	return dw.csvWriter.Error()
+}
+
+func (dw *delimitedSeqAnnotationWriter) Close() error {
+	dw.csvWriter.Flush()
+	return dw.csvWriter.Error()
+}
+
+// jsonSeqAnnotationWriter writes a single indented document holding every field
+// of the sequence annotations, grouped by collection, i.e. '{"Collections": [[...]]}'.
+// Each collection is written as it comes, rather than held until the document is
+// complete, so the document is only valid once closed.
+type jsonSeqAnnotationWriter struct {
+	bw          *bufio.Writer
+	buf         *bytes.Buffer
+	encoder     *json.Encoder
+	collections int
+}
+
+func newJsonSeqAnnotationWriter(w io.Writer) *jsonSeqAnnotationWriter {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent(jsonSeqAnnotationPrefix, jsonIndent)
+	encoder.SetEscapeHTML(false)
+	return &jsonSeqAnnotationWriter{bw: bufio.NewWriter(w), buf: buf, encoder: encoder}
+}
+
+func (jw *jsonSeqAnnotationWriter) WriteCollection(collection []model.SequenceAnnotation) error {
+	if jw.collections == 0 {
+		jw.bw.WriteString(jsonCollectionsStart)
+	} else {
+		jw.bw.WriteString(",")
+	}
+
+	jw.bw.WriteString("\n" + jsonCollectionPrefix + "[")
+	for i, sa := range collection {
+		jw.buf.Reset()
+		if err := jw.encoder.Encode(sa); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			jw.bw.WriteString(",")
+		}
+		jw.bw.WriteString("\n" + jsonSeqAnnotationPrefix)
+		jw.bw.Write(bytes.TrimSuffix(jw.buf.Bytes(), []byte("\n")))
+	}
+
+	if len(collection) > 0 {
+		jw.bw.WriteString("\n" + jsonCollectionPrefix)
+	}
+	jw.bw.WriteString("]")
+	jw.collections++
+	return jw.bw.Flush()
+}
+
+func (jw *jsonSeqAnnotationWriter) Close() error {
+	if jw.collections == 0 {
+		jw.bw.WriteString(jsonCollectionsStart + "]\n}\n")
+	} else {
+		jw.bw.WriteString("\n" + jsonIndent + "]\n}\n")
+	}
+	return jw.bw.Flush()
+}
+
+// jsonlSeqAnnotationWriter writes every field of each sequence annotation as a
+// document on its own line, tagged with the index of its collection.
+type jsonlSeqAnnotationWriter struct {
+	bw         *bufio.Writer
+	encoder    *json.Encoder
+	collection int
+}
+
+func (jw *jsonlSeqAnnotationWriter) WriteCollection(collection []model.SequenceAnnotation) error {
+	for _, sa := range collection {
+		if err := jw.encoder.Encode(collectionSeqAnnotation{jw.collection, sa}); err != nil {
+			return err
+		}
+	}
+
+	jw.collection++
+	return jw.bw.Flush()
+}
+
+func (jw *jsonlSeqAnnotationWriter) Close() error {
+	return jw.bw.Flush()
+}
+
+// protoSeqAnnotationWriter writes each sequence annotation as a protobuf message
+// prefixed by its varint encoded length, in collection order.
+type protoSeqAnnotationWriter struct {
+	bw *bufio.Writer
+}
+
+func (pw *protoSeqAnnotationWriter) WriteCollection(collection []model.SequenceAnnotation) error {
+	for _, sa := range collection {
+		if _, err := protodelim.MarshalTo(pw.bw, pb.FromModel(sa)); err != nil {
+			return err
+		}
+	}
+	return pw.bw.Flush()
+}
+
+func (pw *protoSeqAnnotationWriter) Close() error {
+	return pw.bw.Flush()
+}
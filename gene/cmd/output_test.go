@@ -0,0 +1,461 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/bio-pdv/tools/model"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// testOutputCollections hold descriptions needing quotes in csv.
+	testOutputCollections = [][]model.SequenceAnnotation{
+		{
+			{SequenceId: "REL606", Position: "12,345", Mutation: "A→G", Description: "lipoprotein, putative", Source: "ara-1.gd"},
+			{SequenceId: "REL606", Position: "65,431", Mutation: "+G", Description: `the "abc" operon`, Source: "ara-1.gd"},
+		},
+		{
+			{SequenceId: "REL607", Position: "1,000", Mutation: "Δ1 bp", Description: "line\nbreak", Source: "ara+1.gd"},
+		},
+	}
+)
+
+// readDelimited parses the delimited output back into its records.
+func readDelimited(t *testing.T, data string, delim rune) [][]string {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comma = delim
+	records, err := reader.ReadAll()
+	assert.Nil(t, err)
+	return records
+}
+
+func TestDelimitedSeqAnnotationWriter(t *testing.T) {
+	cases := []struct {
+		name     string
+		delim    rune
+		header   bool
+		columns  []string
+		expected [][]string
+	}{
+		{
+			name:    "CSV With Header",
+			delim:   csvDelimiter,
+			header:  true,
+			columns: defaultColumns,
+			expected: [][]string{
+				defaultColumns,
+				{"0", "REL606", "12,345", "A→G", "", "", "", "lipoprotein, putative", "ara-1.gd"},
+				{"0", "REL606", "65,431", "+G", "", "", "", `the "abc" operon`, "ara-1.gd"},
+				{"1", "REL607", "1,000", "Δ1 bp", "", "", "", "line\nbreak", "ara+1.gd"},
+			},
+		},
+		{
+			name:    "TSV Without Header",
+			delim:   tsvDelimiter,
+			header:  false,
+			columns: defaultColumns,
+			expected: [][]string{
+				{"0", "REL606", "12,345", "A→G", "", "", "", "lipoprotein, putative", "ara-1.gd"},
+				{"0", "REL606", "65,431", "+G", "", "", "", `the "abc" operon`, "ara-1.gd"},
+				{"1", "REL607", "1,000", "Δ1 bp", "", "", "", "line\nbreak", "ara+1.gd"},
+			},
+		},
+		{
+			name:    "Selected Columns",
+			delim:   csvDelimiter,
+			header:  true,
+			columns: []string{"description", " Collection ", "POSITION"},
+			expected: [][]string{
+				{"description", "collection", "position"},
+				{"lipoprotein, putative", "0", "12,345"},
+				{`the "abc" operon`, "0", "65,431"},
+				{"line\nbreak", "1", "1,000"},
+			},
+		},
+		{
+			name:    "Typed Columns",
+			delim:   csvDelimiter,
+			header:  false,
+			columns: []string{"collection", "position_value", "frequency_value"},
+			expected: [][]string{
+				{"0", "0", "0"},
+				{"0", "0", "0"},
+				{"1", "0", "0"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		dw, err := newDelimitedSeqAnnotationWriter(out, c.delim, c.header, c.columns)
+		assert.Nil(t, err, c.name)
+		for _, collection := range testOutputCollections {
+			assert.Nil(t, dw.WriteCollection(collection), c.name)
+		}
+		assert.Nil(t, dw.Close(), c.name)
+		assert.Equal(t, c.expected, readDelimited(t, out.String(), c.delim), c.name)
+	}
+}
+
+func TestDelimitedSeqAnnotationWriterQuoting(t *testing.T) {
+	out := &bytes.Buffer{}
+	dw, err := newDelimitedSeqAnnotationWriter(out, csvDelimiter, false, []string{"position", "description"})
+	assert.Nil(t, err)
+	assert.Nil(t, dw.WriteCollection(testOutputCollections[0]))
+	assert.Nil(t, dw.Close())
+
+	// Fields with a delimiter or quote are quoted, and quotes are doubled, per RFC 4180.
+	assert.Equal(t, "\"12,345\",\"lipoprotein, putative\"\n\"65,431\",\"the \"\"abc\"\" operon\"\n", out.String())
+}
+
+func TestDelimitedSeqAnnotationWriterEmpty(t *testing.T) {
+	out := &bytes.Buffer{}
+	dw, err := newDelimitedSeqAnnotationWriter(out, csvDelimiter, true, []string{"collection", "gene"})
+	assert.Nil(t, err)
+	assert.Nil(t, dw.WriteCollection([]model.SequenceAnnotation{}))
+	assert.Nil(t, dw.Close())
+	assert.Equal(t, "collection,gene\n", out.String())
+}
+
+func TestDelimitedSeqAnnotationWriterUnknownColumn(t *testing.T) {
+	cases := [][]string{
+		{"bogus"},
+		{"position", "frequency%"},
+		{""},
+	}
+
+	for _, columns := range cases {
+		out := &bytes.Buffer{}
+		dw, err := newDelimitedSeqAnnotationWriter(out, csvDelimiter, true, columns)
+		assert.NotNil(t, err, columns)
+		assert.Nil(t, dw, columns)
+		assert.Equal(t, "", out.String(), columns)
+	}
+}
+
+// readJson decodes the json output back into its collections.
+func readJson(t *testing.T, data string) [][]model.SequenceAnnotation {
+	doc := struct {
+		Collections [][]model.SequenceAnnotation
+	}{}
+	assert.Nil(t, json.Unmarshal([]byte(data), &doc))
+	return doc.Collections
+}
+
+// readJsonl decodes each line of the jsonl output back into its sequence annotation.
+func readJsonl(t *testing.T, data string) []collectionSeqAnnotation {
+	lines := []collectionSeqAnnotation{}
+	for _, line := range strings.SplitAfter(data, "\n") {
+		if line == "" {
+			continue
+		}
+
+		assert.True(t, strings.HasSuffix(line, "\n"))
+		decoded := collectionSeqAnnotation{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded))
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestJsonSeqAnnotationWriter(t *testing.T) {
+	cases := []struct {
+		name        string
+		collections [][]model.SequenceAnnotation
+	}{
+		{"Collections", testOutputCollections},
+		{"Empty Collection", [][]model.SequenceAnnotation{{}, testOutputCollections[1]}},
+		{"No Collections", [][]model.SequenceAnnotation{}},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		jw := newJsonSeqAnnotationWriter(out)
+		for i, collection := range c.collections {
+			assert.Nil(t, jw.WriteCollection(collection), c.name)
+
+			// Each collection is written as it comes, rather than on Close.
+			for _, sa := range collection {
+				assert.Contains(t, out.String(), sa.Position, "%s collection: %d", c.name, i)
+			}
+		}
+		assert.Nil(t, jw.Close(), c.name)
+		assert.Equal(t, c.collections, readJson(t, out.String()), c.name)
+
+		// The document is the same as encoding it at once.
+		expected := &bytes.Buffer{}
+		encoder := json.NewEncoder(expected)
+		encoder.SetIndent("", jsonIndent)
+		encoder.SetEscapeHTML(false)
+		assert.Nil(t, encoder.Encode(struct{ Collections [][]model.SequenceAnnotation }{c.collections}), c.name)
+		assert.Equal(t, expected.String(), out.String(), c.name)
+	}
+}
+
+func TestJsonlSeqAnnotationWriter(t *testing.T) {
+	cmd := &cobra.Command{}
+	addOutputFlags(cmd)
+	out := &bytes.Buffer{}
+	jw, err := newSeqAnnotationWriter(cmd, out, jsonlOutputType)
+	assert.Nil(t, err)
+	for _, collection := range testOutputCollections {
+		assert.Nil(t, jw.WriteCollection(collection))
+	}
+	assert.Nil(t, jw.Close())
+
+	assert.Contains(t, out.String(), `"Mutation":"A→G"`)
+	assert.Equal(t, []collectionSeqAnnotation{
+		{0, testOutputCollections[0][0]},
+		{0, testOutputCollections[0][1]},
+		{1, testOutputCollections[1][0]},
+	}, readJsonl(t, out.String()))
+}
+
+func TestParseCmdJsonOutput(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdData)
+	parsed, _, err := executeCommand(t, "", "parse", filePath, "--ot", "jsonl")
+	assert.Nil(t, err)
+	lines := readJsonl(t, parsed)
+	assert.Len(t, lines, 5)
+
+	collection := []model.SequenceAnnotation{}
+	for i, line := range lines {
+		assert.Equal(t, 0, line.Collection, i)
+		assert.Equal(t, filePath, line.Source, i)
+		assert.Equal(t, line.ContentId(), line.UniqueId, i)
+		collection = append(collection, line.SequenceAnnotation)
+	}
+	assert.Equal(t, []string{"1,000", "2,000", "3,000", "4,000", "5,000"}, []string{
+		collection[0].Position, collection[1].Position, collection[2].Position, collection[3].Position, collection[4].Position,
+	})
+
+	// Json holds the same sequence annotations, in one document.
+	stdout, _, err := executeCommand(t, "", "parse", filePath, "--ot", "json")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]model.SequenceAnnotation{collection}, readJson(t, stdout))
+
+	outputPath := filepath.Join(dir, "out.json")
+	_, _, err = executeCommand(t, "", "parse", filePath, "-o", outputPath)
+	assert.Nil(t, err)
+	written, err := ioutil.ReadFile(outputPath)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout, string(written))
+}
+
+func TestParseCmdDelimitedOutput(t *testing.T) {
+	filePath := writeTestFile(t, t.TempDir(), "output.gd", testGdHeader+
+		"SNP\t1\t.\tREL606\t1000\tA\tgene_name=abcA\tgene_product=lipoprotein, putative\n"+
+		"INS\t2\t.\tREL606\t2000\tG\tgene_name=abcB\tgene_product=the \"abc\" operon\n")
+	cases := []struct {
+		name     string
+		args     []string
+		delim    rune
+		expected [][]string
+	}{
+		{
+			name:  "Default Columns",
+			args:  []string{},
+			delim: csvDelimiter,
+			expected: [][]string{
+				defaultColumns,
+				{"0", "REL606", "1,000", "→A", "100%", "", "abcA", "lipoprotein, putative", filePath},
+				{"0", "REL606", "2,000", "+G", "100%", "", "abcB", `the "abc" operon`, filePath},
+			},
+		},
+		{
+			name:  "Selected Columns Without Header",
+			args:  []string{"--columns", "gene,description", "--columns", "collection", "--header=false"},
+			delim: csvDelimiter,
+			expected: [][]string{
+				{"abcA", "lipoprotein, putative", "0"},
+				{"abcB", `the "abc" operon`, "0"},
+			},
+		},
+		{
+			name:  "TSV",
+			args:  []string{"--ot", "tsv", "--columns", "position,description"},
+			delim: tsvDelimiter,
+			expected: [][]string{
+				{"position", "description"},
+				{"1,000", "lipoprotein, putative"},
+				{"2,000", `the "abc" operon`},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		stdout, _, err := executeCommand(t, "", append([]string{"parse", filePath}, c.args...)...)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.expected, readDelimited(t, stdout, c.delim), c.name)
+	}
+
+	stdout, _, err := executeCommand(t, "", "parse", filePath, "--columns", "position,bogus")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Unknown column: 'bogus'")
+	assert.Equal(t, "", stdout)
+}
+
+func TestResolveOutputType(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Default", []string{}, defaultOutputType},
+		{"Output Type", []string{"--ot", "TSV"}, tsvOutputType},
+		{"CSV Extension", []string{"-o", "out.csv"}, csvOutputType},
+		{"TSV Extension", []string{"-o", "out.tsv"}, tsvOutputType},
+		{"JSON Extension", []string{"-o", "out.json"}, jsonOutputType},
+		{"JSONL Extension", []string{"-o", "out.jsonl"}, jsonlOutputType},
+		{"Proto Extension", []string{"-o", "out.pb"}, protoOutputType},
+		{"Upper Case Extension", []string{"-o", "dir.v2/OUT.JSONL"}, jsonlOutputType},
+		{"Output Type Over Extension", []string{"-o", "out.csv", "--ot", "json"}, jsonOutputType},
+		{"Output Type Over Unknown Extension", []string{"-o", "out.txt", "--ot", "csv"}, csvOutputType},
+	}
+
+	for _, c := range cases {
+		cmd := &cobra.Command{}
+		addOutputFlags(cmd)
+		assert.Nil(t, cmd.Flags().Parse(c.args), c.name)
+		testType, testErr := resolveOutputType(cmd)
+		assert.Nil(t, testErr, c.name)
+		assert.Equal(t, c.expected, testType, c.name)
+	}
+}
+
+func TestResolveOutputTypeUnknownExt(t *testing.T) {
+	for _, outputPath := range []string{"out.txt", "out", "out.csv.gz", "out.pb/"} {
+		cmd := &cobra.Command{}
+		addOutputFlags(cmd)
+		assert.Nil(t, cmd.Flags().Parse([]string{"-o", outputPath}), outputPath)
+		testType, testErr := resolveOutputType(cmd)
+		assert.NotNil(t, testErr, outputPath)
+		assert.Contains(t, testErr.Error(), "'"+outputPath+"'", outputPath)
+		assert.Equal(t, "", testType, outputPath)
+	}
+}
+
+// tempFiles returns the names of the temp files writeFileAtomic left in the directory.
+func tempFiles(t *testing.T, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, ".*"+outputTempSuffix))
+	assert.Nil(t, err)
+	return names
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "out.csv")
+	for _, data := range []string{"first\n", "second\n"} {
+		testErr := writeFileAtomic(filePath, func(w io.Writer) error {
+			_, err := io.WriteString(w, data)
+			return err
+		})
+		assert.Nil(t, testErr)
+
+		written, err := ioutil.ReadFile(filePath)
+		assert.Nil(t, err)
+		assert.Equal(t, data, string(written))
+		assert.Empty(t, tempFiles(t, dir))
+	}
+
+	// A new file gets the same mode as os.Create gives it under the umask.
+	created, err := os.Create(filepath.Join(dir, "created.csv"))
+	assert.Nil(t, err)
+	created.Close()
+	expected, err := os.Stat(created.Name())
+	assert.Nil(t, err)
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Mode().Perm(), info.Mode().Perm())
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "out.csv", "original\n")
+	assert.Nil(t, os.Chmod(filePath, 0600))
+	testErr := writeFileAtomic(filePath, func(w io.Writer) error {
+		_, err := io.WriteString(w, "replaced\n")
+		return err
+	})
+	assert.Nil(t, testErr)
+
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	written, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "replaced\n", string(written))
+}
+
+func TestWriteFileAtomicWriteError(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "out.csv", "original\n")
+	writeErr := errors.New("write failed")
+	testErr := writeFileAtomic(filePath, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return writeErr
+	})
+	assert.Equal(t, writeErr, testErr)
+
+	// The original is untouched, and the partial output is removed.
+	written, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "original\n", string(written))
+	assert.Empty(t, tempFiles(t, dir))
+
+	// Nothing is created when there wasn't a file before.
+	newPath := filepath.Join(dir, "new.csv")
+	assert.Equal(t, writeErr, writeFileAtomic(newPath, func(io.Writer) error { return writeErr }))
+	_, err = os.Stat(newPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, tempFiles(t, dir))
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "missing", "out.csv")
+	called := false
+	testErr := writeFileAtomic(filePath, func(io.Writer) error {
+		called = true
+		return nil
+	})
+	assert.NotNil(t, testErr)
+	assert.False(t, called)
+}
+
+func TestParseCmdOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdHeader+testGdData)
+
+	outputPath := filepath.Join(dir, "OUT.TSV")
+	stdout, _, err := executeCommand(t, "", "parse", filePath, "-o", outputPath, "--columns", "position")
+	assert.Nil(t, err)
+	assert.Equal(t, "", stdout)
+	written, err := ioutil.ReadFile(outputPath)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"position"}, {"1,000"}, {"2,000"}, {"3,000"}, {"4,000"}, {"5,000"}}, readDelimited(t, string(written), tsvDelimiter))
+
+	// An unknown extension, or output that can't be written, leaves the earlier output.
+	for _, args := range [][]string{
+		{"parse", filePath, "-o", filepath.Join(dir, "out.txt")},
+		{"parse", filePath, "-o", outputPath, "--columns", "bogus"},
+	} {
+		_, _, err = executeCommand(t, "", args...)
+		assert.NotNil(t, err, args)
+		_, statErr := os.Stat(filepath.Join(dir, "out.txt"))
+		assert.True(t, os.IsNotExist(statErr), args)
+		rewritten, readErr := ioutil.ReadFile(outputPath)
+		assert.Nil(t, readErr, args)
+		assert.Equal(t, string(written), string(rewritten), args)
+		assert.Empty(t, tempFiles(t, dir), args)
+	}
+}
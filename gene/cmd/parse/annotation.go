@@ -0,0 +1,88 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	stopCodon = "*"
+)
+
+var (
+	// aminoAcidRegex matches an amino acid change, optionally followed by its codon
+	// change, e.g. 'V12A (GTG→GGG)'. Trailing notes, like breseq's '†', are ignored.
+	aminoAcidRegex = regexp.MustCompile(`^([A-Z*])(\d+)([A-Z*])(?: \(([ACGTN]{3})→([ACGTN]{3})\))?`)
+	// genePositionRegex matches a position within a gene, e.g. 'coding (123/456 nt)'
+	// or 'pseudogene (123-125/456 nt)'.
+	genePositionRegex = regexp.MustCompile(`^(coding|noncoding|pseudogene) \((\d+)(?:-\d+)?/(\d+) nt\)`)
+	// intergenicRegex matches the distances to the flanking genes, e.g.
+	// 'intergenic (-123/+12)', or 'intergenic (–/+12)' when there's no gene on the left.
+	intergenicRegex = regexp.MustCompile(`^intergenic \(([^/)]+)/([^/)]+)\)`)
+
+	genePositionEffects = map[string]model.EffectCategory{
+		"coding":     model.CodingIndelEffect,
+		"noncoding":  model.NoncodingEffect,
+		"pseudogene": model.PseudogeneEffect,
+	}
+
+	// annotationReplacer normalizes the non-breaking spaces and hyphens, and the minus
+	// signs, that breseq renders in its HTML output.
+	annotationReplacer = strings.NewReplacer("\u00A0", " ", "\u2011", "-", "\u2212", "-")
+)
+
+// parseAnnotation decomposes the annotation of a mutation into its effect on the
+// gene. Returns false with an empty effect if the annotation is of an unknown form.
+func parseAnnotation(annotation string) (model.Effect, bool) {
+	normalized := strings.TrimSpace(annotationReplacer.Replace(annotation))
+	if m := aminoAcidRegex.FindStringSubmatch(normalized); m != nil {
+		return model.Effect{
+			Category:          aminoAcidEffect(m[1], m[3]),
+			AminoAcidRef:      m[1],
+			AminoAcidPosition: parseBaseCount(m[2]),
+			AminoAcidAlt:      m[3],
+			CodonRef:          m[4],
+			CodonAlt:          m[5],
+		}, true
+	}
+
+	if m := genePositionRegex.FindStringSubmatch(normalized); m != nil {
+		return model.Effect{
+			Category:     genePositionEffects[m[1]],
+			GenePosition: parseBaseCount(m[2]),
+			GeneLength:   parseBaseCount(m[3]),
+		}, true
+	}
+
+	if m := intergenicRegex.FindStringSubmatch(normalized); m != nil {
+		left, leftOk := parseGeneDistance(m[1])
+		right, rightOk := parseGeneDistance(m[2])
+		if leftOk && rightOk {
+			return model.Effect{Category: model.IntergenicEffect, LeftGeneDistance: left, RightGeneDistance: right}, true
+		}
+	}
+	return model.Effect{}, false
+}
+
+func aminoAcidEffect(ref string, alt string) model.EffectCategory {
+	if ref == alt {
+		return model.SynonymousEffect
+	} else if alt == stopCodon {
+		return model.NonsenseEffect
+	}
+	return model.MissenseEffect
+}
+
+// parseGeneDistance parses a signed distance to a flanking gene, e.g. '+12'. A dash
+// means there's no gene on that side, so the distance is zero.
+func parseGeneDistance(distance string) (int64, bool) {
+	trimmed := strings.TrimSpace(distance)
+	if trimmed == "–" || trimmed == "-" {
+		return 0, true
+	}
+
+	n, err := strconv.ParseInt(strings.Replace(trimmed, positionSeparator, "", -1), 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	cases := []struct {
+		annotation string
+		expected   model.Effect
+	}{
+		{"V12A (GTG→GGG)", model.Effect{Category: model.MissenseEffect, AminoAcidRef: "V", AminoAcidPosition: 12,
+			AminoAcidAlt: "A", CodonRef: "GTG", CodonAlt: "GGG"}},
+		{"V12V (GTG→GTA)", model.Effect{Category: model.SynonymousEffect, AminoAcidRef: "V", AminoAcidPosition: 12,
+			AminoAcidAlt: "V", CodonRef: "GTG", CodonAlt: "GTA"}},
+		{"Q123*\u00A0(CAG→TAG)\u00A0†", model.Effect{Category: model.NonsenseEffect, AminoAcidRef: "Q", AminoAcidPosition: 123,
+			AminoAcidAlt: "*", CodonRef: "CAG", CodonAlt: "TAG"}},
+		{"*45W", model.Effect{Category: model.MissenseEffect, AminoAcidRef: "*", AminoAcidPosition: 45, AminoAcidAlt: "W"}},
+		{"intergenic (-123/+12)", model.Effect{Category: model.IntergenicEffect, LeftGeneDistance: -123, RightGeneDistance: 12}},
+		{"intergenic\u00A0(‑1,234/–)", model.Effect{Category: model.IntergenicEffect, LeftGeneDistance: -1234}},
+		{"coding (123/456 nt)", model.Effect{Category: model.CodingIndelEffect, GenePosition: 123, GeneLength: 456}},
+		{"coding (123-125/456 nt)", model.Effect{Category: model.CodingIndelEffect, GenePosition: 123, GeneLength: 456}},
+		{"noncoding (12/76 nt)", model.Effect{Category: model.NoncodingEffect, GenePosition: 12, GeneLength: 76}},
+		{"pseudogene (300/900 nt)", model.Effect{Category: model.PseudogeneEffect, GenePosition: 300, GeneLength: 900}},
+	}
+
+	for _, c := range cases {
+		effect, ok := parseAnnotation(c.annotation)
+		assert.True(t, ok, c.annotation)
+		assert.Equal(t, c.expected, effect, c.annotation)
+	}
+}
+
+func TestParseAnnotationUnknown(t *testing.T) {
+	for _, annotation := range []string{"", "abcdefg (ꯍ567/+89)", "intergenic (abc/+12)", "coding (nt)"} {
+		effect, ok := parseAnnotation(annotation)
+		assert.False(t, ok, annotation)
+		assert.Equal(t, model.Effect{}, effect, annotation)
+	}
+}
@@ -1,9 +1,8 @@
 package parse
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	// TODO Change to protobuf model
 	"github.com/bio-pdv/tools/model"
 	"io"
 	"log"
@@ -19,15 +18,17 @@ const (
 	htmlFileType            fileType    = "html"
 	breseq                  application = "breseq"
 	breseqVers027Number     appVersion  = "0.27"
+	breseqLatestVersNumber  appVersion  = "0.38"
 	nonBreakingSpaceUnicode             = "\u00A0"
 	newlineChar                         = "\n"
 	emptyChar                           = " "
 	minExpectedTables                   = 2
 	minExpectedVersCols                 = 2
 	minExpectedHeaderRows               = 2
-	// TODO Support breseq gd format.
 
-	errInvalidBreseq027HtmlFile  = "breseq 0.27.* HTML file format is the only supported file type right now."
+	errInvalidBreseqHtmlFileFmt  = "Not a breseq %s.* HTML file."
+	errOpenFileMsgFmt            = "Could not open file for parsing. Error: '%w'"
+	errBreseqVersMismatchMsgFmt  = "Expected a breseq %s.* HTML file, but got version: '%s'"
 	errInvalidVersTableMsgFmt    = "Invalid Version Table. Error: '%s'\n"
 	errVersNotFound              = "Version not found"
 	errNoRows                    = "No rows"
@@ -35,34 +36,37 @@ const (
 	errInvalidDataTableMsgFmt    = "Invalid Data Table. Error: '%s'\n"
 	errMismatchedLenDTableMsgFmt = "Data table header lengths don't match. Expected: '%d', but got: '%d' headers"
 	errMismatchedDataTableMsgFmt = "Data table headers don't match. At index: '%d' expected: '%s', but got: '%s'"
+	errNoMatchingLayoutMsgFmt    = "Data table headers don't match any breseq %s.* layout"
 
-	validVersTableMsg = "Valid Version Table"
-	validDataTableMsg = "Valid Data Table"
+	validVersTableMsg    = "Valid Version Table"
+	validDataTableMsgFmt = "Valid Data Table with the '%s' layout"
 )
 
-var (
-	breseqVers027Prefix = strings.Join([]string{
-		"breseq",
-		"version",
-		string(breseqVers027Number),
-	}, "")
-
-	breseq027HtmlDataHeaders = []string{
-		"evidence",
-		"seq\u00A0id",
-		"position",
-		"mutation",
-		"freq",
-		"annotation",
-		"gene",
-		"description",
+// Options configures how sequence annotation files are parsed. The zero value parses strictly.
+type Options struct {
+	// Lenient repairs malformed files with the parsers implementing LenientParser, e.g.
+	// implicitly closing the <td> and <tr> tags left open in breseq HTML files, rather
+	// than stopping at the first problem. Other parsers parse the same either way.
+	Lenient bool
+	// OnWarning, if set, receives each repair made in lenient mode.
+	OnWarning WarningHandler
+}
+
+// withFilePath sets the filepath on the warnings handed to OnWarning.
+func (o Options) withFilePath(filePath string) Options {
+	if onWarning := o.OnWarning; onWarning != nil {
+		o.OnWarning = func(warning *ParseError) {
+			warning.FilePath = filePath
+			onWarning(warning)
+		}
 	}
-)
+	return o
+}
 
 // MustParseSeqAnnotationDataFilePath is the same as the ParseSeqAnnotationDataFilePath except it
 // panics on the error.
-func MustParseSeqAnnotationDataFilePath(filePath string, fileType string, appName string, version string) [][]model.SequenceAnnotation {
-	result, err := ParseSeqAnnotationDataFilePath(filePath, fileType, appName, version)
+func MustParseSeqAnnotationDataFilePath(filePath string, fileType string, appName string, version string, opts Options) [][]model.SequenceAnnotation {
+	result, err := ParseSeqAnnotationDataFilePath(filePath, fileType, appName, version, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -71,181 +75,366 @@ func MustParseSeqAnnotationDataFilePath(filePath string, fileType string, appNam
 }
 
 // ParseSeqAnnotationDataFilePath is a filepath-based version of the ParseSeqAnnotationData function.
-func ParseSeqAnnotationDataFilePath(filePath string, fileType string, appName string, version string) ([][]model.SequenceAnnotation, error) {
-	reader, err := os.Open(filePath)
+// Each sequence annotation's Source is set to the filepath. Errors are returned as a *ParseError
+// holding the filepath, and match os.ErrNotExist etc. if the file can't be opened. Warnings hold
+// the filepath too.
+func ParseSeqAnnotationDataFilePath(filePath string, fileType string, appName string, version string, opts Options) ([][]model.SequenceAnnotation, error) {
+	return parseSeqAnnotationDataFilePath(context.Background(), filePath, fileType, appName, version, opts)
+}
+
+// parseSeqAnnotationDataFilePath is the same as ParseSeqAnnotationDataFilePath, except reading
+// the file fails with the context's error once it's cancelled, stopping the parsing.
+func parseSeqAnnotationDataFilePath(ctx context.Context, filePath string, fileType string, appName string, version string, opts Options) ([][]model.SequenceAnnotation, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("Could not open file for parsing. Filepath: '%s'\n", filePath)
+		return nil, withFilePath(fmt.Errorf(errOpenFileMsgFmt, err), filePath)
 	}
+	defer file.Close()
 
-	results, err := ParseSeqAnnotationData(reader, fileType, appName, version)
+	reader := &contextReader{ctx: ctx, reader: file}
+	results, err := ParseSeqAnnotationData(reader, fileType, appName, version, opts.withFilePath(filePath))
 	if err != nil {
-		return nil, fmt.Errorf("%s Filepath: %s", err.Error(), filePath)
+		return nil, withFilePath(err, filePath)
 	}
 
+	for _, collection := range results {
+		for i := range collection {
+			setSource(&collection[i], filePath)
+		}
+	}
 	return results, nil
 }
 
 // ParseSeqAnnotationData is the main method for tokenizing and validating the sequence annotation data
 // via the reader interface. It verifies the following:
-//  * Sequence annotation file is of an html type. There's a TODO to support gd and other file formats
-//    like JSON on an as needed basis.
-//  * Sequence annotation file is generated by a supported application type and version. Currently,
-//    it only supports breseq version 0.27.*
+//  * Sequence annotation file type, application, and version has a registered parser. Out of the box,
+//    breseq's html and gd (GenomeDiff) files are supported for the versions registered in
+//    breseqHtmlLayouts, i.e. 0.27.* through 0.38.* See RegisterParser to add other formats.
 //  * Ensures the file contains a valid signature and data table. The first table is expected to contain
 //    the version row. The second table is expected to contain a single arbitrary row, header, then immediately
-//    followed by the data rows.
+//    followed by the data rows. The header must match one of the layouts registered for the version.
+//
+// Any of the file type, application, or version left empty is auto-detected by asking each of the
+// registered parsers whether they recognize the beginning of the reader.
+//
+// Each sequence annotation the parser leaves without a UniqueId is given its ContentId.
+//
+// The opts choose whether malformed files are repaired, see Options.
 //
 // Returns a slice of slices where each slice represents a single table data was collected from. It can pick up
-// multiple tables, if they exist. If there are any errors in parsing, those are returned.
-func ParseSeqAnnotationData(reader io.Reader, fileType string, appName string, version string) ([][]model.SequenceAnnotation, error) {
-	err := validateSeqAnnotationDataFile(fileType, appName, version)
+// multiple tables, if they exist. If there are any errors in parsing, those are returned. They match one of
+// ErrUnsupportedFormat, ErrVersionMismatch, ErrMalformedTable, or ErrHeaderMismatch with errors.Is where it
+// applies, and errors.As finds the *ParseError locating the table and row, or line, where that's known.
+func ParseSeqAnnotationData(reader io.Reader, fileType string, appName string, version string, opts Options) ([][]model.SequenceAnnotation, error) {
+	parser, reader, err := findParser(reader, fileType, appName, version)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseBreseq027HtmlFile(reader)
+	var results [][]model.SequenceAnnotation
+	if lenientParser, ok := parser.(LenientParser); ok && opts.Lenient {
+		results = [][]model.SequenceAnnotation{}
+		err = lenientParser.ParseLenient(reader, collectSeqAnnotations(&results), opts.OnWarning)
+	} else {
+		results, err = parser.Parse(reader)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	assignUniqueIds(results)
+	return results, nil
 }
 
-func validateSeqAnnotationDataFile(fType string, appName string, version string) error {
-	if fileType(strings.ToLower(fType)) != htmlFileType || application(appName) != breseq || appVersion(version) != breseqVers027Number {
-		return errors.New(errInvalidBreseq027HtmlFile)
+// ParseSeqAnnotationDataFilePathStream is a filepath-based version of the ParseSeqAnnotationDataStream
+// function. Each sequence annotation's Source, and each warning's FilePath, is set to the filepath.
+func ParseSeqAnnotationDataFilePathStream(filePath string, fileType string, appName string, version string, opts Options, handler SeqAnnotationHandler) error {
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return withFilePath(fmt.Errorf(errOpenFileMsgFmt, err), filePath)
 	}
+	defer reader.Close()
+
+	err = ParseSeqAnnotationDataStream(reader, fileType, appName, version, opts.withFilePath(filePath), func(collection int, sa model.SequenceAnnotation) error {
+		setSource(&sa, filePath)
+		return handler(collection, sa)
+	})
 
+	if err != nil {
+		return withFilePath(err, filePath)
+	}
 	return nil
 }
 
-func isBreseq027(tables []table) bool {
-	if len(tables) < minExpectedTables {
-		return false
+// ParseSeqAnnotationDataStream is the streaming version of the ParseSeqAnnotationData function.
+// Each sequence annotation is handed to the handler, along with the index of its collection,
+// as soon as it's read, rather than the whole file being held in memory. Parsers that don't
+// implement StreamParser, e.g. GenomeDiff files whose mutations refer to evidence further
+// down the file, parse the whole file first and then hand off each sequence annotation.
+//
+// Returns the first error from parsing or the handler. Sequence annotations handed off
+// before a parsing error are not taken back.
+func ParseSeqAnnotationDataStream(reader io.Reader, fileType string, appName string, version string, opts Options, handler SeqAnnotationHandler) error {
+	parser, reader, err := findParser(reader, fileType, appName, version)
+	if err != nil {
+		return err
 	}
 
-	if !isBreseq027VersTable(tables[0]) {
-		log.Println("Invalid Version Table")
-		return false
+	assignUniqueId := func(collection int, sa model.SequenceAnnotation) error {
+		if sa.UniqueId == "" {
+			sa.UniqueId = sa.ContentId()
+		}
+		return handler(collection, sa)
 	}
 
-	if !isBreseq027DataTable(tables[1]) {
-		log.Println("Invalid Data Table")
-		return false
+	if lenientParser, ok := parser.(LenientParser); ok && opts.Lenient {
+		return lenientParser.ParseLenient(reader, assignUniqueId, opts.OnWarning)
+	} else if streamParser, ok := parser.(StreamParser); ok {
+		return streamParser.ParseStream(reader, assignUniqueId)
 	}
 
-	return true
+	results, err := parser.Parse(reader)
+	if err != nil {
+		return err
+	}
+
+	for i, collection := range results {
+		for _, sa := range collection {
+			if err = assignUniqueId(i, sa); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-// isBreseq027VersTable checks that the table has a breseq version 0.27
-// prefix in the first row of the table.
-func isBreseq027VersTable(tTable table) bool {
+// setSource sets the filepath the sequence annotation was parsed from. A UniqueId that
+// was the sequence annotation's ContentId is re-derived with the filepath.
+func setSource(sa *model.SequenceAnnotation, filePath string) {
+	hasContentId := sa.UniqueId == sa.ContentId()
+	sa.Source = filePath
+	if hasContentId {
+		sa.UniqueId = sa.ContentId()
+	}
+}
+
+// assignUniqueIds gives the sequence annotations without a UniqueId their ContentId.
+func assignUniqueIds(results [][]model.SequenceAnnotation) {
+	for _, collection := range results {
+		for i := range collection {
+			if collection[i].UniqueId == "" {
+				collection[i].UniqueId = collection[i].ContentId()
+			}
+		}
+	}
+}
+
+// parseBreseqVersTable extracts the major.minor version from the
+// first row of the table, e.g. '0.27' from 'breseq version 0.27.1'.
+func parseBreseqVersTable(tTable table) (appVersion, bool) {
 	if len(tTable) <= 0 {
-		log.Println(errInvalidVersTableMsgFmt, errNoRows)
-		return false
+		log.Printf(errInvalidVersTableMsgFmt, errNoRows)
+		return "", false
 	}
 
 	versRow := tTable[0]
 	if len(versRow) < minExpectedVersCols {
-		log.Println(errInvalidVersTableMsgFmt, errNotEnoughContent)
-		return false
+		log.Printf(errInvalidVersTableMsgFmt, errNotEnoughContent)
+		return "", false
 	}
 
 	versCol := strings.Replace(versRow[1], nonBreakingSpaceUnicode, "", -1)
 	versCol = strings.Replace(versCol, newlineChar, "", -1)
 	versCol = strings.Replace(versCol, emptyChar, "", -1)
 
-	log.Printf("Validating version: '%s' with prefix: '%s'\n", versCol, breseqVers027Prefix)
-	if !strings.HasPrefix(versCol, breseqVers027Prefix) {
+	matches := breseqVersPattern.FindStringSubmatch(versCol)
+	if matches == nil {
 		log.Printf(errInvalidVersTableMsgFmt, errVersNotFound)
-		return false
+		return "", false
+	}
+
+	return appVersion(matches[1]), true
+}
+
+// checkBreseqVersTable checks that the table has a breseq version prefix in the first
+// row of the table matching the given version, returning why it doesn't. A table
+// without a breseq version is ErrUnsupportedFormat, while one with another version
+// is ErrVersionMismatch.
+func checkBreseqVersTable(tTable table, version appVersion) error {
+	vers, ok := parseBreseqVersTable(tTable)
+	if !ok {
+		return &ParseError{Table: 1, Err: newSentinelError(ErrUnsupportedFormat, fmt.Errorf(errInvalidBreseqHtmlFileFmt, version))}
+	}
+
+	log.Printf("Validating version: '%s' with expected version: '%s'\n", vers, version)
+	if vers != version {
+		log.Printf(errInvalidVersTableMsgFmt, errVersNotFound)
+		return &ParseError{Table: 1, Err: newSentinelError(ErrVersionMismatch, fmt.Errorf(errBreseqVersMismatchMsgFmt, version, vers))}
 	}
 
 	log.Println(validVersTableMsg)
-	return true
+	return nil
 }
 
-// isBreseq027DataTable checks that the table has at least
-// a valid breseq 0.27 header row.
-func isBreseq027DataTable(tTable table) bool {
+// findBreseqDataTableLayout checks that the table has at least a header row matching
+// one of the layouts registered for the breseq version. Returns the matching layout.
+func findBreseqDataTableLayout(tTable table, version appVersion) (breseqHtmlLayout, bool) {
 	if len(tTable) <= 0 {
 		log.Printf(errInvalidDataTableMsgFmt, errNoRows)
-		return false
+		return breseqHtmlLayout{}, false
 	}
 
 	if len(tTable) < minExpectedHeaderRows {
 		log.Printf(errInvalidDataTableMsgFmt, errNotEnoughContent)
-		return false
+		return breseqHtmlLayout{}, false
 	}
 
 	// Throw away the first row with the string, "Predicted mutations"
 	tHeader := tTable[1]
-	if len(tHeader) <= 0 || len(tHeader) != len(breseq027HtmlDataHeaders) {
-		log.Printf(errMismatchedLenDTableMsgFmt, len(breseq027HtmlDataHeaders), len(tHeader))
+	for _, layout := range breseqHtmlLayouts[version] {
+		if isBreseqDataTableHeader(tHeader, layout.headers) {
+			log.Printf(validDataTableMsgFmt, layout.name)
+			return layout, true
+		}
+	}
+
+	log.Printf(errNoMatchingLayoutMsgFmt, version)
+	return breseqHtmlLayout{}, false
+}
+
+func isBreseqDataTableHeader(tHeader row, headers []string) bool {
+	if len(tHeader) <= 0 || len(tHeader) != len(headers) {
+		log.Printf(errMismatchedLenDTableMsgFmt, len(headers), len(tHeader))
 		return false
 	}
 
-	for i, header := range breseq027HtmlDataHeaders {
+	for i, header := range headers {
 		if tHeader[i] != header {
-			log.Printf(errMismatchedDataTableMsgFmt, i, tHeader[i], header)
+			log.Printf(errMismatchedDataTableMsgFmt, i, header, tHeader[i])
 			return false
 		}
 	}
 
-	log.Println(validDataTableMsg)
 	return true
 }
 
-func parseBreseq027HtmlFile(reader io.Reader) ([][]model.SequenceAnnotation, error) {
+func parseBreseqHtmlFile(reader io.Reader, version appVersion) ([][]model.SequenceAnnotation, error) {
 	results := [][]model.SequenceAnnotation{}
-	tables, err := parseDataTableHtmlTokenizer(reader)
-	if err != nil {
+	if err := streamBreseqHtmlFile(reader, version, collectSeqAnnotations(&results), nil); err != nil {
 		return nil, err
 	}
+	return results, nil
+}
+
+// streamBreseqHtmlFile is the streaming version of parseBreseqHtmlFile. Each data row is
+// converted and handed off as soon as its closing tag is parsed, so only the version table,
+// and the throw away and header rows of each data table, are ever kept. The html is parsed
+// leniently, with each repair handed to onWarning, if it's given.
+func streamBreseqHtmlFile(reader io.Reader, version appVersion, handler SeqAnnotationHandler, onWarning WarningHandler) error {
+	headerRows := table{}
+	var layout breseqHtmlLayout
+	hasLayout, validDataTable, checkedVers := false, false, false
+	tableIndex, rowIndex, collection := 0, 0, -1
+	tables, err := streamDataTableHtmlTokenizer(reader, func(i int, r row) error {
+		// The first row of the first table holds the version. It's validated as soon as
+		// it's read, so errors are located at the version row rather than a later table.
+		if i == 0 {
+			if checkedVers {
+				return nil
+			}
+			checkedVers = true
+			return checkBreseqVersTable(table{r}, version)
+		}
+
+		if i != tableIndex {
+			// The first table didn't have any rows.
+			if !checkedVers {
+				checkedVers = true
+				if err := checkBreseqVersTable(table{}, version); err != nil {
+					return err
+				}
+			}
+
+			if i > 1 && !validDataTable {
+				return newBreseqDataTableError(errNotEnoughContent)
+			}
+
+			tableIndex, rowIndex, hasLayout = i, 0, false
+			headerRows = table{}
+			collection++
+		}
+
+		rowIndex++
+		// Skip the throw away and header rows, after matching the header to a layout.
+		//  * First row is just the string, "Predicted mutations".
+		//  * Second row is the header matching the layout.
+		if len(headerRows) < minExpectedHeaderRows {
+			headerRows = append(headerRows, r)
+			if len(headerRows) == minExpectedHeaderRows {
+				layout, hasLayout = findBreseqDataTableLayout(headerRows, version)
+				if i == 1 && !hasLayout {
+					return &ParseError{Table: i + 1, Row: rowIndex, Err: newSentinelError(ErrHeaderMismatch,
+						fmt.Errorf(errNoMatchingLayoutMsgFmt, version))}
+				}
+				validDataTable = validDataTable || hasLayout
+			}
+			return nil
+		}
+
+		if !hasLayout {
+			return nil
+		}
+
+		sa := changeBreseqRowToSeqAnnotation(r, layout, version)
+		if err := populateTypedFields(&sa); err != nil {
+			return &ParseError{Table: i + 1, Row: rowIndex, Err: newSentinelError(ErrMalformedTable,
+				fmt.Errorf(errMalformedRowMsgFmt, i+1, rowIndex, err.Error()))}
+		}
+		return handler(collection, sa)
+	}, onWarning)
+
+	if err != nil {
+		return err
+	}
 
-	if !isBreseq027(tables) {
-		return nil, fmt.Errorf(errInvalidBreseq027HtmlFile)
+	if tables < minExpectedTables {
+		log.Println("Invalid Data Table")
+		return newSentinelError(ErrUnsupportedFormat, fmt.Errorf(errInvalidBreseqHtmlFileFmt, version))
 	}
 
-	// Skip the first table, which is the versions table.
-	for i := 1; i < len(tables); i++ {
-		table := tables[i]
-		if len(table) > 0 {
-			sATable := changeBreseq027TableToSeqAnnotation(table)
-			results = append(results, sATable)
+	// Without any rows, the version table is yet to be validated.
+	if !checkedVers {
+		if err := checkBreseqVersTable(table{}, version); err != nil {
+			return err
 		}
 	}
-	return results, nil
+
+	if !validDataTable {
+		return newBreseqDataTableError(errNotEnoughContent)
+	}
+	return nil
 }
 
-// changeBreseq027TableToSeqAnnotation assumes the ordering of the columns were verified
-// to be in order before running.
-func changeBreseq027TableToSeqAnnotation(dataTable table) []model.SequenceAnnotation {
-	if !isBreseq027DataTable(dataTable) {
-		return nil
-	}
-
-	results := []model.SequenceAnnotation{}
-	// Skip the throw away and header rows.
-	//  * First row is just the string, "Predicted mutations".
-	//  * Second row is the header with:
-	//     * evidence
-	//     * seq id
-	//     * position
-	//     * mutation
-	//     * freq
-	//     * annotation
-	//     * gene
-	//     * description
-	for i := 2; i < len(dataTable); i++ {
-		dataRow := dataTable[i]
-		sa := model.SequenceAnnotation{
-			SequenceId:  dataRow[1],
-			Position:    dataRow[2],
-			Mutation:    dataRow[3],
-			Frequency:   dataRow[4],
-			Annotation:  dataRow[5],
-			Gene:        dataRow[6],
-			Description: dataRow[7],
-			Application: string(breseq),
-			AppVersion:  string(breseqVers027Number),
+// newBreseqDataTableError reports a first data table without enough rows for the
+// throw away and header rows.
+func newBreseqDataTableError(reason string) error {
+	log.Printf(errInvalidDataTableMsgFmt, reason)
+	return &ParseError{Table: minExpectedTables, Err: newSentinelError(ErrMalformedTable,
+		fmt.Errorf(strings.TrimRight(errInvalidDataTableMsgFmt, "\n"), reason))}
+}
+
+// changeBreseqRowToSeqAnnotation maps each column of the data row to its sequence
+// annotation field through the header layout.
+func changeBreseqRowToSeqAnnotation(dataRow row, layout breseqHtmlLayout, version appVersion) model.SequenceAnnotation {
+	sa := model.SequenceAnnotation{
+		Application: string(breseq),
+		AppVersion:  string(version),
+	}
+	for j, header := range layout.headers {
+		if setter := breseqColumnSetters[header]; setter != nil && j < len(dataRow) {
+			setter(&sa, dataRow[j])
 		}
-		results = append(results, sa)
 	}
-	return results
+	return sa
 }
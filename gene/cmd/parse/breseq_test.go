@@ -1,11 +1,15 @@
 package parse
 
 import (
+	"errors"
 	"fmt"
+	"github.com/bio-pdv/tools/model"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/html"
 	"io/ioutil"
 	"log"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -18,8 +22,10 @@ const (
 	testEvidence       = "AB"
 	testSeqId          = "AB0123456"
 	testPosition       = "12,345"
+	testPositionValue  = 12345
 	testMutation       = "+G"
 	testFrequency      = "12%"
+	testFrequencyValue = 0.12
 	testAnnotation     = "abcdefg&nbsp;(&#1234;567/+89)"
 	testGene           = "<i>ABC0123</i>&nbsp;&larr;&nbsp;/&nbsp;&larr;&nbsp;<i>ABC5678</i>"
 	expectedTestGene   = "ABC0123&nbsp;&larr;&nbsp;/&nbsp;&larr;&nbsp;ABC5678"
@@ -85,9 +91,9 @@ tr {background-color: rgb(255,255,255);}
 <tr class="normal_table_row">
 <td align="center"><a href="evidence/INS_0.html">` + testEvidence + testSuffix0 + `</a></td><!-- Evidence -->
 <td align="center">` + testSeqId + testSuffix0 + `</td><!-- Seq_Id -->
-<td align="right">` + testPosition + testSuffix0 + `</td><!-- Position -->
+<td align="right">` + testPosition + `</td><!-- Position -->
 <td align="center">` + testMutation + testSuffix0 + `</td><!-- Cell Mutation -->
-<td align="right">` + testFrequency + testSuffix0 + `</td>
+<td align="right">` + testFrequency + `</td>
 <td align="center">` + testAnnotation + testSuffix0 + `</td>
 <td align="center">` + testGene + testSuffix0 + `</td>
 <td align="left">` + testDescription + testSuffix0 + `</td>
@@ -98,9 +104,9 @@ tr {background-color: rgb(255,255,255);}
 <tr class="normal_table_row">
 <td align="center"><a href="evidence/INS_0.html">` + testEvidence + testSuffix1 + `</a></td><!-- Evidence -->
 <td align="center">` + testSeqId + testSuffix1 + `</td><!-- Seq_Id -->
-<td align="right">` + testPosition + testSuffix1 + `</td><!-- Position -->
+<td align="right">` + testPosition + `</td><!-- Position -->
 <td align="center">` + testMutation + testSuffix1 + `</td><!-- Cell Mutation -->
-<td align="right">` + testFrequency + testSuffix1 + `</td>
+<td align="right">` + testFrequency + `</td>
 <td align="center">` + testAnnotation + testSuffix1 + `</td>
 <td align="center">` + testGene + testSuffix1 + `</td>
 <td align="left">` + testDescription + testSuffix1 + `</td>
@@ -122,7 +128,10 @@ var (
 		"ABC0123</i>&nbsp;&larr;&nbsp;/&nbsp;&larr;&nbsp;DV4567",
 		"abcdefghijklmnopqrstuvwxyz",
 	}
-	throwAwayRow = row{"throw away row"}
+	throwAwayRow        = row{"throw away row"}
+	breseqVers027Prefix = "breseqversion" + string(breseqVers027Number)
+	// testBreseqHtmlDataHeaders are the headers of a 0.27 data table in polymorphism mode.
+	testBreseqHtmlDataHeaders = breseqHtmlLayouts[breseqVers027Number][0].headers
 )
 
 func init() {
@@ -131,7 +140,7 @@ func init() {
 
 func TestParseBreseq027HtmlFile(t *testing.T) {
 	testReader := strings.NewReader(validBreseq027Html)
-	testResults, testErr := parseBreseq027HtmlFile(testReader)
+	testResults, testErr := parseBreseqHtmlFile(testReader, breseqVers027Number)
 	assert.Nil(t, testErr)
 	assert.NotNil(t, testResults)
 	assert.Equal(t, 1, len(testResults))
@@ -140,25 +149,137 @@ func TestParseBreseq027HtmlFile(t *testing.T) {
 	for i, rowRes := range testResults[0] {
 		iStr := fmt.Sprintf("%d", i)
 		assert.Equal(t, html.UnescapeString(testSeqId+testSuffix+iStr), rowRes.SequenceId)
-		assert.Equal(t, html.UnescapeString(testPosition+testSuffix+iStr), rowRes.Position)
+		assert.Equal(t, testPosition, rowRes.Position)
+		assert.Equal(t, int64(testPositionValue), rowRes.PositionValue)
 		assert.Equal(t, html.UnescapeString(testMutation+testSuffix+iStr), rowRes.Mutation)
-		assert.Equal(t, html.UnescapeString(testFrequency+testSuffix+iStr), rowRes.Frequency)
+		assert.Equal(t, testFrequency, rowRes.Frequency)
+		assert.InDelta(t, testFrequencyValue, rowRes.FrequencyValue, 1e-9)
 		assert.Equal(t, html.UnescapeString(testAnnotation+testSuffix+iStr), rowRes.Annotation)
 		assert.Equal(t, html.UnescapeString(expectedTestGene+testSuffix+iStr), rowRes.Gene)
+		assert.Equal(t, []string{"ABC0123", "ABC5678" + testSuffix + iStr}, rowRes.Genes)
+		assert.Equal(t, model.GeneContext{LeftGene: "ABC0123", LeftStrand: "-", RightGene: "ABC5678" + testSuffix + iStr,
+			RightStrand: "-"}, rowRes.GeneContext)
 		assert.Equal(t, html.UnescapeString(testDescription+testSuffix+iStr), rowRes.Description)
 	}
 }
 
-func TestChangeBreseq027TableToSeqAnnotation(t *testing.T) {
-	testTable := table{
-		row{"throw away header"},
-		row(breseq027HtmlDataHeaders),
-		dataRow,
+func TestStreamBreseq027HtmlFile(t *testing.T) {
+	expected, testErr := parseBreseqHtmlFile(strings.NewReader(validBreseq027Html), breseqVers027Number)
+	assert.Nil(t, testErr)
+
+	collections, sas := []int{}, []model.SequenceAnnotation{}
+	testErr = streamBreseqHtmlFile(strings.NewReader(validBreseq027Html), breseqVers027Number, func(collection int, sa model.SequenceAnnotation) error {
+		collections = append(collections, collection)
+		sas = append(sas, sa)
+		return nil
+	}, nil)
+	assert.Nil(t, testErr)
+	assert.Equal(t, []int{0, 0}, collections)
+	assert.Equal(t, expected[0], sas)
+
+	// The handler's error stops the stream at the first row.
+	handlerErr := fmt.Errorf("stop")
+	handled := 0
+	testErr = streamBreseqHtmlFile(strings.NewReader(validBreseq027Html), breseqVers027Number, func(int, model.SequenceAnnotation) error {
+		handled++
+		return handlerErr
+	}, nil)
+	assert.Equal(t, handlerErr, testErr)
+	assert.Equal(t, 1, handled)
+
+	testErr = streamBreseqHtmlFile(strings.NewReader(wellFormedHtmlTableString), breseqVers027Number, func(int, model.SequenceAnnotation) error {
+		return nil
+	}, nil)
+	assert.NotNil(t, testErr)
+}
+
+func TestParseSeqAnnotationDataStream(t *testing.T) {
+	for _, data := range []string{validBreseq027Html, validBreseqGd} {
+		expected, testErr := ParseSeqAnnotationData(strings.NewReader(data), "", "", "0.27", Options{})
+		assert.Nil(t, testErr)
+
+		results := [][]model.SequenceAnnotation{}
+		testErr = ParseSeqAnnotationDataStream(strings.NewReader(data), "", "", "0.27", Options{}, collectSeqAnnotations(&results))
+		assert.Nil(t, testErr)
+		assert.Equal(t, expected, results)
+		assert.NotEmpty(t, results[0][0].UniqueId)
+	}
+
+	testErr := ParseSeqAnnotationDataStream(strings.NewReader(testVcfHeader), "", "", "", Options{}, collectSeqAnnotations(&[][]model.SequenceAnnotation{}))
+	assert.NotNil(t, testErr)
+}
+
+func TestParseSeqAnnotationDataLenient(t *testing.T) {
+	expected, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseq027Html), "", "", "", Options{})
+	assert.Nil(t, testErr)
+
+	// Browsers tolerate the columns left open, as does lenient mode, auto-detection included.
+	unclosedCols := regexp.MustCompile(`</td>(<!--[^>]*-->)?\n(<td|</tr)`)
+	malformed := unclosedCols.ReplaceAllString(validBreseq027Html, "$1$2")
+	_, testErr = ParseSeqAnnotationData(strings.NewReader(malformed), "", "", "", Options{})
+	assert.True(t, errors.Is(testErr, ErrMalformedTable))
+
+	warnings := []*ParseError{}
+	opts := Options{Lenient: true, OnWarning: func(warning *ParseError) {
+		warnings = append(warnings, warning)
+	}}
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(malformed), "", "", "", opts)
+	assert.Nil(t, testErr)
+	assert.Equal(t, expected, testResults)
+	assert.Len(t, warnings, len(unclosedCols.FindAllString(validBreseq027Html, -1)))
+
+	results := [][]model.SequenceAnnotation{}
+	testErr = ParseSeqAnnotationDataStream(strings.NewReader(malformed), "", "", "", Options{Lenient: true}, collectSeqAnnotations(&results))
+	assert.Nil(t, testErr)
+	assert.Equal(t, expected, results)
+
+	// Warnings hold the filepath too.
+	filePath := filepath.Join(t.TempDir(), "index.html")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(malformed), 0600))
+	warnings = []*ParseError{}
+	_, testErr = ParseSeqAnnotationDataFilePath(filePath, "", "", "", opts)
+	assert.Nil(t, testErr)
+	assert.NotEmpty(t, warnings)
+	assert.Equal(t, filePath, warnings[0].FilePath)
+
+	// Parsers that aren't lenient parse the same either way.
+	expected, testErr = ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "", "", "0.27", Options{})
+	assert.Nil(t, testErr)
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "", "", "0.27", opts)
+	assert.Nil(t, testErr)
+	assert.Equal(t, expected, testResults)
+}
+
+func TestParseSeqAnnotationDataFilePathUniqueIds(t *testing.T) {
+	dir := t.TempDir()
+	parseFile := func(name string) model.SequenceAnnotation {
+		filePath := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(filePath, []byte(validBreseq027Html), 0600))
+		results, err := ParseSeqAnnotationDataFilePath(filePath, "", "", "", Options{})
+		assert.Nil(t, err)
+
+		streamed := [][]model.SequenceAnnotation{}
+		assert.Nil(t, ParseSeqAnnotationDataFilePathStream(filePath, "", "", "", Options{}, collectSeqAnnotations(&streamed)))
+		assert.Equal(t, results, streamed)
+		return results[0][0]
 	}
-	testSaTable := changeBreseq027TableToSeqAnnotation(testTable)
-	assert.Equal(t, 1, len(testSaTable))
 
-	testSa := testSaTable[0]
+	// The same mutation called in different files is from different samples.
+	first, second := parseFile("first.html"), parseFile("second.html")
+	assert.Equal(t, first.ContentId(), first.UniqueId)
+	assert.Equal(t, second.ContentId(), second.UniqueId)
+	assert.NotEqual(t, first.UniqueId, second.UniqueId)
+	assert.Equal(t, first.UniqueId, parseFile("first.html").UniqueId)
+
+	// Unless a sample sheet says they're the same sample.
+	sample := Sample{Population: "Ara-1", Generation: "500"}
+	sample.StampSeqAnnotation(&first)
+	sample.StampSeqAnnotation(&second)
+	assert.Equal(t, first.UniqueId, second.UniqueId)
+}
+
+func TestChangeBreseq027RowToSeqAnnotation(t *testing.T) {
+	testSa := changeBreseqRowToSeqAnnotation(dataRow, breseqHtmlLayouts[breseqVers027Number][0], breseqVers027Number)
 	assert.Equal(t, dataRow[1], testSa.SequenceId)
 	assert.Equal(t, dataRow[2], testSa.Position)
 	assert.Equal(t, dataRow[3], testSa.Mutation)
@@ -171,16 +292,15 @@ func TestChangeBreseq027TableToSeqAnnotation(t *testing.T) {
 	assert.Equal(t, testSa.Generation, "")
 }
 
-func TestChangeBreseq027TableToSeqAnnotationNoHeader(t *testing.T) {
-	testTable := table{
-		dataRow,
-	}
-
-	testSaTable := changeBreseq027TableToSeqAnnotation(testTable)
-	assert.Nil(t, testSaTable)
+func TestChangeBreseq027RowToSeqAnnotationShortRow(t *testing.T) {
+	testSa := changeBreseqRowToSeqAnnotation(dataRow[:3], breseqHtmlLayouts[breseqVers027Number][0], breseqVers027Number)
+	assert.Equal(t, dataRow[1], testSa.SequenceId)
+	assert.Equal(t, dataRow[2], testSa.Position)
+	assert.Equal(t, "", testSa.Mutation)
+	assert.Equal(t, "", testSa.Description)
 }
 
-func TestIsBreseq027VersTable(t *testing.T) {
+func TestCheckBreseq027VersTable(t *testing.T) {
 	cases := []struct {
 		name      string
 		testTable table
@@ -218,11 +338,11 @@ func TestIsBreseq027VersTable(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		assert.True(t, isBreseq027VersTable(c.testTable), c.name)
+		assert.Nil(t, checkBreseqVersTable(c.testTable, breseqVers027Number), c.name)
 	}
 }
 
-func TestIsBreseq027InvalidVersTable(t *testing.T) {
+func TestCheckBreseq027InvalidVersTable(t *testing.T) {
 	cases := []struct {
 		name      string
 		testTable table
@@ -258,21 +378,23 @@ func TestIsBreseq027InvalidVersTable(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		assert.False(t, isBreseq027VersTable(c.testTable), c.name)
+		assert.NotNil(t, checkBreseqVersTable(c.testTable, breseqVers027Number), c.name)
 	}
 }
 
-func TestIsBreseq027DataTable(t *testing.T) {
+func TestFindBreseq027DataTableLayout(t *testing.T) {
 	dataTable := table{
 		row{"throw away row"},
-		row(breseq027HtmlDataHeaders),
+		row(testBreseqHtmlDataHeaders),
 		row{"test row"},
 	}
 
-	assert.True(t, isBreseq027DataTable(dataTable))
+	layout, ok := findBreseqDataTableLayout(dataTable, breseqVers027Number)
+	assert.True(t, ok)
+	assert.Equal(t, polymorphismLayoutName, layout.name)
 }
 
-func TestIsBreseq027InvalidDataTable(t *testing.T) {
+func TestFindBreseq027DataTableLayoutInvalid(t *testing.T) {
 	cases := []struct {
 		name      string
 		testTable table
@@ -299,7 +421,7 @@ func TestIsBreseq027InvalidDataTable(t *testing.T) {
 			name: "Mismatched Headers",
 			testTable: table{
 				throwAwayRow,
-				row(append(breseq027HtmlDataHeaders[3:], breseq027HtmlDataHeaders[:3]...)),
+				row(append(testBreseqHtmlDataHeaders[3:], testBreseqHtmlDataHeaders[:3]...)),
 				row{"data"},
 			},
 		},
@@ -307,7 +429,7 @@ func TestIsBreseq027InvalidDataTable(t *testing.T) {
 			name: "Not Enough Headers",
 			testTable: table{
 				throwAwayRow,
-				row(breseq027HtmlDataHeaders[:4]),
+				row(testBreseqHtmlDataHeaders[:4]),
 				row{"data"},
 			},
 		},
@@ -322,45 +444,7 @@ func TestIsBreseq027InvalidDataTable(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		assert.False(t, isBreseq027DataTable(c.testTable), c.name)
-	}
-}
-
-func TestIsBreseq027(t *testing.T) {
-	versTable := table{
-		row{blankCol, breseqVers027Prefix + ".12345"},
+		_, ok := findBreseqDataTableLayout(c.testTable, breseqVers027Number)
+		assert.False(t, ok, c.name)
 	}
-	headerTable := table{
-		throwAwayRow,
-		row(breseq027HtmlDataHeaders),
-	}
-	testTables := []table{
-		versTable,
-		headerTable,
-	}
-
-	assert.True(t, isBreseq027(testTables))
-}
-
-func TestIsBreseq027NotEnoughTables(t *testing.T) {
-	notEnoughTables := []table{
-		table{
-			row{"blank col", breseqVers027Prefix + ".12345"},
-		},
-	}
-
-	versTable := table{
-		row{blankCol, breseqVers027Prefix + ".12345"},
-	}
-	headerTable := table{
-		throwAwayRow,
-		row(breseq027HtmlDataHeaders),
-	}
-	swappedTables := []table{
-		headerTable,
-		versTable,
-	}
-
-	assert.False(t, isBreseq027(notEnoughTables))
-	assert.False(t, isBreseq027(swappedTables))
 }
@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"regexp"
+)
+
+// breseqHtmlLayout describes one arrangement of the data table headers
+// found in a breseq index.html file.
+type breseqHtmlLayout struct {
+	name    string
+	headers []string
+}
+
+const (
+	polymorphismLayoutName = "polymorphism"
+	clonalLayoutName       = "clonal"
+)
+
+// breseqColumnSetter copies a data table column's value into its sequence annotation field.
+type breseqColumnSetter func(sa *model.SequenceAnnotation, value string)
+
+var (
+	// breseqVersPattern captures the major.minor version out of a version
+	// row with all of its whitespace removed, e.g. 'breseqversion0.35.1'.
+	breseqVersPattern = regexp.MustCompile(`^breseqversion(\d+\.\d+)`)
+
+	// appVersionPattern captures the major.minor version out of a requested
+	// version, e.g. '0.35', '0.35.1', or '0.35.*'.
+	appVersionPattern = regexp.MustCompile(`^(\d+\.\d+)(\.\d+|\.\*)?$`)
+
+	// breseqDefaultHtmlLayouts are the header rows of the data tables breseq outputs. Run
+	// in polymorphism mode, breseq adds the freq column of each mutation's frequency,
+	// which clonal runs leave out as every mutation is fixed. Layouts are tried in order
+	// and the first one with matching headers is used.
+	breseqDefaultHtmlLayouts = []breseqHtmlLayout{
+		{polymorphismLayoutName, []string{"evidence", "seq\u00A0id", "position", "mutation", "freq", "annotation", "gene", "description"}},
+		{clonalLayoutName, []string{"evidence", "seq\u00A0id", "position", "mutation", "annotation", "gene", "description"}},
+	}
+
+	// breseqHtmlLayouts is the registry of every supported breseq release, keyed by its
+	// major.minor version, to the layouts of the data tables that release outputs. Every
+	// release so far shares the default layouts. A release with a different layout only
+	// needs its own layouts here, and a fixture for each in testdata/breseq.
+	breseqHtmlLayouts = map[appVersion][]breseqHtmlLayout{
+		breseqVers027Number:    breseqDefaultHtmlLayouts,
+		"0.28":                 breseqDefaultHtmlLayouts,
+		"0.29":                 breseqDefaultHtmlLayouts,
+		"0.30":                 breseqDefaultHtmlLayouts,
+		"0.31":                 breseqDefaultHtmlLayouts,
+		"0.32":                 breseqDefaultHtmlLayouts,
+		"0.33":                 breseqDefaultHtmlLayouts,
+		"0.34":                 breseqDefaultHtmlLayouts,
+		"0.35":                 breseqDefaultHtmlLayouts,
+		"0.36":                 breseqDefaultHtmlLayouts,
+		"0.37":                 breseqDefaultHtmlLayouts,
+		breseqLatestVersNumber: breseqDefaultHtmlLayouts,
+	}
+
+	// breseqColumnSetters maps every data table header to the sequence annotation field
+	// it populates. Headers deliberately left out have a nil setter, like evidence, which
+	// only links to breseq's evidence pages.
+	breseqColumnSetters = map[string]breseqColumnSetter{
+		"evidence":    nil,
+		"seq\u00A0id": func(sa *model.SequenceAnnotation, v string) { sa.SequenceId = v },
+		"position":    func(sa *model.SequenceAnnotation, v string) { sa.Position = v },
+		"mutation":    func(sa *model.SequenceAnnotation, v string) { sa.Mutation = v },
+		"freq":        func(sa *model.SequenceAnnotation, v string) { sa.Frequency = v },
+		"annotation":  func(sa *model.SequenceAnnotation, v string) { sa.Annotation = v },
+		"gene":        func(sa *model.SequenceAnnotation, v string) { sa.Gene = v },
+		"description": func(sa *model.SequenceAnnotation, v string) { sa.Description = v },
+	}
+)
@@ -0,0 +1,224 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// testBreseqFixtureSeqAnnotations are the rows of every breseq fixture in
+	// testdata/breseq, without the frequencies clonal runs leave out.
+	testBreseqFixtureSeqAnnotations = []model.SequenceAnnotation{
+		{
+			SequenceId:  "REL606",
+			Position:    "70,867",
+			Mutation:    "A→C",
+			Frequency:   "34.5%",
+			Annotation:  "Y123D\u00A0(TAT→GAT)\u00A0",
+			Gene:        "araA\u00A0←",
+			Description: "L\u2011arabinose isomerase",
+		},
+		{
+			SequenceId:  "REL606",
+			Position:    "1,733,268",
+			Mutation:    "+G",
+			Frequency:   "100%",
+			Annotation:  "intergenic\u00A0(\u201120/+113)",
+			Gene:        "ycgF\u00A0→\u00A0/\u00A0←\u00A0ycgZ",
+			Description: "predicted FAD\u2011binding phosphodiesterase/conserved hypothetical protein",
+		},
+	}
+	testBreseqFixtureFrequencyValues = []float64{0.345, 1.0}
+)
+
+// readBreseqHtmlFixture reads the index.html excerpt of a breseq run in the mode of the
+// layout, e.g. testdata/breseq/clonal.html, as if it was output by the version.
+func readBreseqHtmlFixture(t *testing.T, version string, layoutName string) string {
+	data, err := ioutil.ReadFile(breseqHtmlFixturePath(layoutName))
+	assert.Nil(t, err)
+	fixtureVersRow := "version " + string(breseqLatestVersNumber) + ".0"
+	assert.Contains(t, string(data), fixtureVersRow)
+	return strings.Replace(string(data), fixtureVersRow, "version "+version, 1)
+}
+
+func breseqHtmlFixturePath(layoutName string) string {
+	return filepath.Join("testdata", "breseq", layoutName+".html")
+}
+
+func TestBreseqHtmlLayouts(t *testing.T) {
+	layoutHeaders := map[string][]string{}
+	for vers, layouts := range breseqHtmlLayouts {
+		assert.Equal(t, []string{polymorphismLayoutName, clonalLayoutName}, []string{layouts[0].name, layouts[1].name}, vers)
+		clonalHeaders := map[string]bool{}
+		for _, header := range layouts[1].headers {
+			clonalHeaders[header] = true
+		}
+
+		for _, layout := range layouts {
+			name := fmt.Sprintf("%s %s", vers, layout.name)
+
+			// Each distinct layout has its own name, and fixture.
+			if headers, ok := layoutHeaders[layout.name]; ok {
+				assert.Equal(t, headers, layout.headers, name)
+			}
+			layoutHeaders[layout.name] = layout.headers
+			_, err := os.Stat(breseqHtmlFixturePath(layout.name))
+			assert.Nil(t, err, name)
+
+			// Every header is either mapped to a field or deliberately ignored.
+			for _, header := range layout.headers {
+				setter, ok := breseqColumnSetters[header]
+				assert.True(t, ok, "%s header: %s", name, header)
+
+				// The extra polymorphism mode columns must all be mapped.
+				if !clonalHeaders[header] {
+					assert.NotNil(t, setter, "%s header: %s", name, header)
+				}
+			}
+		}
+	}
+
+	// The only extra polymorphism mode column is the frequency.
+	sa := model.SequenceAnnotation{}
+	breseqColumnSetters["freq"](&sa, testFrequency)
+	assert.Equal(t, model.SequenceAnnotation{Frequency: testFrequency}, sa)
+	assert.Nil(t, breseqColumnSetters["evidence"])
+}
+
+func TestParseBreseqHtmlFileVersions(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected appVersion
+	}{
+		{"0.27.0", breseqVers027Number},
+		{"0.28.1", "0.28"},
+		{"0.29.0", "0.29"},
+		{"0.30.2", "0.30"},
+		{"0.31.1", "0.31"},
+		{"0.32.0", "0.32"},
+		{"0.33.2", "0.33"},
+		{"0.34.0", "0.34"},
+		{"0.35.1", "0.35"},
+		{"0.36.0", "0.36"},
+		{"0.37.1", "0.37"},
+		{"0.38.0", breseqLatestVersNumber},
+		{"0.38.12", breseqLatestVersNumber},
+	}
+
+	versions := map[appVersion]bool{}
+	for _, c := range cases {
+		versions[c.expected] = true
+		for _, layoutName := range []string{polymorphismLayoutName, clonalLayoutName} {
+			name := fmt.Sprintf("%s %s", c.version, layoutName)
+			isClonal := layoutName == clonalLayoutName
+			testReader := strings.NewReader(readBreseqHtmlFixture(t, c.version, layoutName))
+			testResults, testErr := parseBreseqHtmlFile(testReader, c.expected)
+			assert.Nil(t, testErr, name)
+			assert.Equal(t, 1, len(testResults), name)
+			if len(testResults) != 1 {
+				continue
+			}
+
+			assert.Equal(t, len(testBreseqFixtureSeqAnnotations), len(testResults[0]), name)
+			for i, rowRes := range testResults[0] {
+				expected := testBreseqFixtureSeqAnnotations[i]
+				assert.Equal(t, expected.SequenceId, rowRes.SequenceId, name)
+				assert.Equal(t, expected.Position, rowRes.Position, name)
+				assert.Equal(t, expected.Mutation, rowRes.Mutation, name)
+				assert.Equal(t, expected.Annotation, rowRes.Annotation, name)
+				assert.Equal(t, expected.Gene, rowRes.Gene, name)
+				assert.Equal(t, expected.Description, rowRes.Description, name)
+				assert.Equal(t, string(breseq), rowRes.Application, name)
+				assert.Equal(t, string(c.expected), rowRes.AppVersion, name)
+				if isClonal {
+					assert.Equal(t, "", rowRes.Frequency, name)
+					assert.Equal(t, 1.0, rowRes.FrequencyValue, name)
+				} else {
+					assert.Equal(t, expected.Frequency, rowRes.Frequency, name)
+					assert.InDelta(t, testBreseqFixtureFrequencyValues[i], rowRes.FrequencyValue, 1e-9, name)
+				}
+			}
+
+			// The version is detected from the file too.
+			detected, testErr := ParseSeqAnnotationData(strings.NewReader(readBreseqHtmlFixture(t, c.version, layoutName)), "", "", "", Options{})
+			assert.Nil(t, testErr, name)
+			assignUniqueIds(testResults)
+			assert.Equal(t, testResults, detected, name)
+		}
+	}
+
+	// Every supported release is covered.
+	for vers := range breseqHtmlLayouts {
+		assert.True(t, versions[vers], vers)
+	}
+}
+
+func TestParseBreseqHtmlFileMismatchedVersion(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		sentinel error
+	}{
+		{"Mismatched Version", readBreseqHtmlFixture(t, "0.35.1", polymorphismLayoutName), ErrVersionMismatch},
+		{"Missing Version", readBreseqHtmlFixture(t, "", clonalLayoutName), ErrUnsupportedFormat},
+	}
+
+	for _, c := range cases {
+		for _, opts := range []Options{{}, {Lenient: true}} {
+			name := fmt.Sprintf("%s Lenient: %t", c.name, opts.Lenient)
+			testResults, testErr := ParseSeqAnnotationData(strings.NewReader(c.data), "html", "breseq", "0.27", opts)
+			assert.Nil(t, testResults, name)
+			assert.True(t, errors.Is(testErr, c.sentinel), name)
+
+			// The error is located at the end of the version row, rather than in the data table.
+			var parseErr *ParseError
+			assert.True(t, errors.As(testErr, &parseErr), name)
+			if parseErr != nil {
+				assert.Equal(t, 1, parseErr.Table, name)
+				assert.Equal(t, 21, parseErr.Line, name)
+				assert.Equal(t, 6, parseErr.Column, name)
+				assert.True(t, strings.HasSuffix(parseErr.Snippet, "</td></tr>"), name)
+				assert.NotContains(t, parseErr.Snippet, "Predicted mutations", name)
+			}
+		}
+	}
+}
+
+func TestParseSeqAnnotationDataVersions(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+	}{
+		{"Major Minor", "0.38"},
+		{"Patch", "0.38.1"},
+		{"Wildcard", "0.38.*"},
+		{"Detected", ""},
+	}
+
+	for _, c := range cases {
+		testReader := strings.NewReader(readBreseqHtmlFixture(t, "0.38.1", clonalLayoutName))
+		testResults, testErr := ParseSeqAnnotationData(testReader, "html", "breseq", c.version, Options{})
+		assert.Nil(t, testErr, c.name)
+		assert.Equal(t, 1, len(testResults), c.name)
+	}
+}
+
+func TestChangeBreseqClonalRowToSeqAnnotation(t *testing.T) {
+	testRow := row{"evid", "AB_012345", "12,456", "+G", "intergenic (-1/+2)", "abcA", "lipoprotein"}
+	testSa := changeBreseqRowToSeqAnnotation(testRow, breseqHtmlLayouts["0.30"][1], "0.30")
+	assert.Equal(t, "AB_012345", testSa.SequenceId)
+	assert.Equal(t, "12,456", testSa.Position)
+	assert.Equal(t, "+G", testSa.Mutation)
+	assert.Equal(t, "", testSa.Frequency)
+	assert.Equal(t, "intergenic (-1/+2)", testSa.Annotation)
+	assert.Equal(t, "abcA", testSa.Gene)
+	assert.Equal(t, "lipoprotein", testSa.Description)
+	assert.Equal(t, "0.30", testSa.AppVersion)
+}
@@ -0,0 +1,146 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+)
+
+const (
+	vcfFileType          fileType = "vcf"
+	detectPeekSize                = 64 * 1024
+	vcfHeaderPrefix               = "##fileformat=VCF"
+	vcfSourcePrefix               = "##source="
+	gdProgramKey                  = "PROGRAM"
+	htmlTagSignature              = "<html"
+	htmlTableSignature            = "<table"
+	htmlDoctypeSignature          = "<!doctype html"
+
+	errUndetectableFileMsg      = "Could not detect the file type. Expected a breseq HTML file, or a GenomeDiff file starting with '#=GENOME_DIFF'"
+	errUnsupportedVcfFileMsgFmt = "Detected a VCF file, generated by application: '%s' version: '%s', but VCF files aren't supported. Please parse the GenomeDiff file it was converted from instead"
+	detectedFileMsgFmt          = "Detected file type: '%s' application: '%s' version: '%s'\n"
+)
+
+var (
+	// breseqProgramPattern captures the version out of a GenomeDiff '#=PROGRAM'
+	// metadata value or a VCF '##source=' header, e.g. 'breseq 0.35.1' or
+	// 'breseq_GD2VCF_converter 0.35.1'.
+	breseqProgramPattern = regexp.MustCompile(`breseq\S*\s+(\d+\.\d+(\.\d+)?)`)
+)
+
+// Detection is what could be sniffed from the beginning of a sequence annotation file.
+// Any field that could not be detected is left empty.
+type Detection struct {
+	FileType    string
+	Application string
+	Version     string
+}
+
+// detectSeqAnnotationData inspects the first bytes of the reader to find the file type,
+// and the application and version that generated it. The following are recognized:
+//  * breseq HTML files, by the version row of the first table.
+//  * GenomeDiff files, by the '#=GENOME_DIFF' header and the '#=PROGRAM' metadata.
+//  * VCF files, by the '##fileformat=VCF' header and the '##source=' metadata.
+//
+// Since the sniffed bytes are consumed from the reader, they're returned along with a
+// replacement reader which replays them before the rest of the data. If no file type
+// matches, the detection is left empty.
+func detectSeqAnnotationData(reader io.Reader) (Detection, []byte, io.Reader, error) {
+	bufReader := bufio.NewReaderSize(reader, detectPeekSize)
+	prefix, err := bufReader.Peek(detectPeekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return Detection{}, nil, nil, err
+	}
+
+	detection, ok := detectSeqAnnotationPrefix(prefix)
+	if ok {
+		log.Printf(detectedFileMsgFmt, detection.FileType, detection.Application, detection.Version)
+	}
+	return detection, prefix, bufReader, nil
+}
+
+// detectSeqAnnotationPrefix matches the prefix against each of the known
+// file signatures. Returns false if there is no match.
+func detectSeqAnnotationPrefix(prefix []byte) (Detection, bool) {
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n\uFEFF")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(gdHeaderPrefix)):
+		return detectGdPrefix(trimmed), true
+	case bytes.HasPrefix(trimmed, []byte(vcfHeaderPrefix)):
+		return detectVcfPrefix(trimmed), true
+	case isHtmlPrefix(trimmed):
+		return detectHtmlPrefix(trimmed), true
+	}
+
+	return Detection{}, false
+}
+
+func isHtmlPrefix(prefix []byte) bool {
+	lower := bytes.ToLower(prefix)
+	return bytes.HasPrefix(lower, []byte(htmlDoctypeSignature)) ||
+		bytes.Contains(lower, []byte(htmlTagSignature)) ||
+		bytes.Contains(lower, []byte(htmlTableSignature))
+}
+
+// detectHtmlPrefix tokenizes the tables found in the prefix and reads the
+// breseq version from the first one. The tables are tokenized leniently, so
+// a malformed file, or a truncated trailing table, is still detected and left
+// for the parser to report.
+func detectHtmlPrefix(prefix []byte) Detection {
+	detection := Detection{FileType: string(htmlFileType)}
+	tables, err := tokenizeDataTableHtml(bytes.NewReader(prefix), nil, func(*ParseError) {})
+	if err != nil || len(tables) <= 0 {
+		return detection
+	}
+
+	if vers, ok := parseBreseqVersTable(tables[0]); ok {
+		detection.Application = string(breseq)
+		detection.Version = string(vers)
+	}
+	return detection
+}
+
+// detectGdPrefix reads the application and version out of the GenomeDiff
+// metadata lines. GenomeDiff is breseq's own format, so the application
+// is always breseq.
+func detectGdPrefix(prefix []byte) Detection {
+	detection := Detection{
+		FileType:    string(gdFileType),
+		Application: string(breseq),
+	}
+	for _, line := range strings.Split(string(prefix), newlineChar) {
+		if !strings.HasPrefix(line, gdMetadataPrefix) {
+			break
+		}
+
+		key, value := splitGdMetadata(strings.TrimRight(line, "\r"))
+		if key == gdProgramKey {
+			if matches := breseqProgramPattern.FindStringSubmatch(value); matches != nil {
+				detection.Version = matches[1]
+			}
+		}
+	}
+	return detection
+}
+
+// detectVcfPrefix reads the application and version out of the '##source='
+// header, which breseq sets when converting a GenomeDiff file to VCF.
+func detectVcfPrefix(prefix []byte) Detection {
+	detection := Detection{FileType: string(vcfFileType)}
+	for _, line := range strings.Split(string(prefix), newlineChar) {
+		if !strings.HasPrefix(line, "##") {
+			break
+		}
+
+		if strings.HasPrefix(line, vcfSourcePrefix) && strings.Contains(line, string(breseq)) {
+			detection.Application = string(breseq)
+			if matches := breseqProgramPattern.FindStringSubmatch(line); matches != nil {
+				detection.Version = matches[1]
+			}
+		}
+	}
+	return detection
+}
@@ -0,0 +1,131 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const (
+	testGdProgramHeader = "#=GENOME_DIFF\t1.0\n#=PROGRAM\tbreseq 0.35.1 revision abc123\n"
+	testVcfHeader       = "##fileformat=VCFv4.1\n##source=breseq_GD2VCF_converter 0.33.2\n#CHROM\tPOS\tID\tREF\tALT\n"
+)
+
+func TestDetectSeqAnnotationData(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected Detection
+	}{
+		{
+			name:     "breseq HTML",
+			data:     validBreseq027Html,
+			expected: Detection{"html", "breseq", "0.27"},
+		},
+		{
+			name:     "breseq HTML Newer Version",
+			data:     readBreseqHtmlFixture(t, "0.36.0", polymorphismLayoutName),
+			expected: Detection{"html", "breseq", "0.36"},
+		},
+		{
+			name:     "Unknown HTML",
+			data:     wellFormedHtmlTableString,
+			expected: Detection{"html", "", ""},
+		},
+		{
+			name:     "GenomeDiff With Program",
+			data:     testGdProgramHeader + "INS\t1\t.\tREL606\t12345\tG\n",
+			expected: Detection{"gd", "breseq", "0.35.1"},
+		},
+		{
+			name:     "GenomeDiff Without Program",
+			data:     validBreseqGd,
+			expected: Detection{"gd", "breseq", ""},
+		},
+		{
+			name:     "Leading Whitespace",
+			data:     "\n\n" + testGdProgramHeader,
+			expected: Detection{"gd", "breseq", "0.35.1"},
+		},
+		{
+			name:     "VCF",
+			data:     testVcfHeader,
+			expected: Detection{"vcf", "breseq", "0.33.2"},
+		},
+	}
+
+	for _, c := range cases {
+		detection, prefix, reader, err := detectSeqAnnotationData(strings.NewReader(c.data))
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.expected, detection, c.name)
+		assert.Equal(t, c.data, string(prefix), c.name)
+
+		replayed, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.data, string(replayed), c.name)
+	}
+}
+
+func TestDetectSeqAnnotationDataUndetectable(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"Empty", ""},
+		{"Plain Text", "seq_id,position,mutation\n"},
+		{"JSON", `{"seq_id": "REL606"}`},
+	}
+
+	for _, c := range cases {
+		detection, _, reader, err := detectSeqAnnotationData(strings.NewReader(c.data))
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, Detection{}, detection, c.name)
+
+		// The reader is still replayed for the parsers that sniff the prefix themselves.
+		replayed, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.data, string(replayed), c.name)
+	}
+}
+
+func TestParseSeqAnnotationDataAutoDetect(t *testing.T) {
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseq027Html), "", "", "", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, 1, len(testResults))
+	assert.Equal(t, 2, len(testResults[0]))
+
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader(testGdProgramHeader+"INS\t1\t.\tREL606\t12345\tG\n"), "", "", "", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, 1, len(testResults))
+	assert.Equal(t, "0.35", testResults[0][0].AppVersion)
+}
+
+func TestParseSeqAnnotationDataAutoDetectPartial(t *testing.T) {
+	// The version can't be detected without the '#=PROGRAM' metadata, so the newest is used.
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "", "", "", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, string(breseqLatestVersNumber), testResults[0][0].AppVersion)
+
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "", "", "0.30", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, 8, len(testResults[0]))
+}
+
+func TestParseSeqAnnotationDataAutoDetectUnsupported(t *testing.T) {
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(testVcfHeader), "", "", "", Options{})
+	assert.True(t, errors.Is(testErr, ErrUnsupportedFormat))
+	assert.Contains(t, testErr.Error(), fmt.Sprintf(errUnsupportedVcfFileMsgFmt, "breseq", "0.33.2"))
+	assert.Nil(t, testResults)
+
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader("seq_id,position,mutation\n"), "", "", "", Options{})
+	assert.True(t, errors.Is(testErr, ErrUnsupportedFormat))
+	assert.Contains(t, testErr.Error(), errUndetectableFileMsg)
+	assert.Nil(t, testResults)
+
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader(wellFormedHtmlTableString), "", "", "", Options{})
+	assert.NotNil(t, testErr)
+	assert.Nil(t, testResults)
+}
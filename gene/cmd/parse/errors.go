@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedFormat is matched by errors about files no registered parser handles,
+	// e.g. an undetectable file type or a file missing the format's header.
+	ErrUnsupportedFormat = errors.New("Unsupported sequence annotation format")
+	// ErrVersionMismatch is matched by errors about files generated by another version
+	// of the application than the parser handles.
+	ErrVersionMismatch = errors.New("Application version mismatch")
+	// ErrMalformedTable is matched by errors about tables, rows, or entries that can't
+	// be parsed, e.g. mismatched HTML tags or a malformed position.
+	ErrMalformedTable = errors.New("Malformed table")
+	// ErrHeaderMismatch is matched by errors about data table headers that don't match
+	// any layout of the application version.
+	ErrHeaderMismatch = errors.New("Table header mismatch")
+)
+
+// sentinelError keeps the message of a detailed error, while matching one of the
+// sentinel errors with errors.Is.
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func newSentinelError(sentinel error, err error) error {
+	return &sentinelError{sentinel, err}
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.err
+}
+
+// ParseError is the error parsing a sequence annotation file, along with where in
+// the file it happened. It wraps the cause, so errors.Is matches the sentinel errors
+// and errors like os.ErrNotExist. The location fields are 1-based, and 0 if unknown.
+type ParseError struct {
+	FilePath string
+	// Table and Row locate the error in an HTML file's tables, counting every table
+	// and row in the file.
+	Table int
+	Row   int
+	// Line locates the error in a line-based file, e.g. a GenomeDiff file. In an HTML
+	// file, Line, Column, and the 0-based byte Offset locate the token where parsing
+	// stopped, e.g. a mismatched tag or the closing tag of a malformed row. Columns
+	// count bytes.
+	Line   int
+	Column int
+	Offset int64
+	// Snippet is the raw HTML leading up to, and including, the token.
+	Snippet string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	msg := strings.TrimRight(e.Err.Error(), "\n")
+	if e.Column > 0 {
+		msg = fmt.Sprintf("%s Line: %d Column: %d Offset: %d", msg, e.Line, e.Column, e.Offset)
+	}
+
+	if e.Snippet != "" {
+		msg = fmt.Sprintf("%s Near: %q", msg, e.Snippet)
+	}
+
+	if e.FilePath != "" {
+		msg = fmt.Sprintf("%s Filepath: %s", msg, e.FilePath)
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// withFilePath sets the filepath on the error, wrapping it in a ParseError if it isn't one.
+func withFilePath(err error, filePath string) error {
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.FilePath = filePath
+		return parseErr
+	}
+	return &ParseError{FilePath: filePath, Err: err}
+}
@@ -0,0 +1,96 @@
+package parse
+
+import (
+	"errors"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name     string
+		data     string
+		version  string
+		sentinel error
+		location ParseError
+		// HTML errors found while tokenizing are located at a token.
+		atToken bool
+	}{
+		{"Unsupported Format", testVcfHeader, "", ErrUnsupportedFormat, ParseError{}, false},
+		{"Missing GenomeDiff Header", "SNP\t1\t.\tREL606\t12345\tG\n", "0.27", ErrUnsupportedFormat, ParseError{}, false},
+		{"Version Mismatch", validBreseq027Html, "0.30", ErrVersionMismatch, ParseError{Table: 1}, true},
+		{"Header Mismatch", strings.Replace(validBreseq027Html, "<th>freq</th>", "<th>frequency</th>", 1), "0.27",
+			ErrHeaderMismatch, ParseError{Table: 2, Row: 2}, true},
+		{"Malformed Row", strings.Replace(validBreseq027Html, testPosition, "12,34x", 1), "0.27",
+			ErrMalformedTable, ParseError{Table: 2, Row: 3}, true},
+		{"Mismatched Tags", strings.Replace(validBreseq027Html, "</tr>\n<!-- End Table Row -->\n</table>", "</table>", 1), "0.27",
+			ErrMalformedTable, ParseError{}, true},
+		{"Malformed GenomeDiff Line", validBreseqGd + "SNP\t20\t.\tREL606\n", "0.27", ErrMalformedTable,
+			ParseError{Line: strings.Count(validBreseqGd, "\n") + 1}, false},
+	}
+
+	sentinels := []error{ErrUnsupportedFormat, ErrVersionMismatch, ErrMalformedTable, ErrHeaderMismatch}
+	for i, c := range cases {
+		fileType := ""
+		if c.version != "" {
+			fileType = "html"
+			if !strings.HasPrefix(c.data, "<") {
+				fileType = "gd"
+			}
+		}
+
+		filePath := filepath.Join(dir, string(rune('a'+i)))
+		assert.Nil(t, ioutil.WriteFile(filePath, []byte(c.data), 0600))
+		_, err := ParseSeqAnnotationDataFilePath(filePath, fileType, "breseq", c.version, Options{})
+		assert.NotNil(t, err, c.name)
+		for _, sentinel := range sentinels {
+			assert.Equal(t, sentinel == c.sentinel, errors.Is(err, sentinel), c.name)
+		}
+
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr), c.name)
+		assert.Equal(t, filePath, parseErr.FilePath, c.name)
+		assert.Equal(t, c.location.Table, parseErr.Table, c.name)
+		assert.Equal(t, c.location.Row, parseErr.Row, c.name)
+		assert.Contains(t, err.Error(), filePath, c.name)
+		if c.atToken {
+			assert.True(t, parseErr.Line > 0 && parseErr.Column > 0 && parseErr.Offset > 0, c.name)
+			assert.NotEmpty(t, parseErr.Snippet, c.name)
+			assert.Contains(t, err.Error(), "Line: ", c.name)
+		} else {
+			assert.Equal(t, c.location.Line, parseErr.Line, c.name)
+			assert.Equal(t, 0, parseErr.Column, c.name)
+		}
+	}
+}
+
+func TestParseErrorsMissingFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "missing.html")
+	_, err := ParseSeqAnnotationDataFilePath(filePath, "", "", "", Options{})
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, filePath, parseErr.FilePath)
+
+	err = ParseSeqAnnotationDataFilePathStream(filePath, "", "", "", Options{}, func(int, model.SequenceAnnotation) error { return nil })
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestParseErrorsHandler(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "index.html")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(validBreseq027Html), 0600))
+
+	handlerErr := errors.New("stop")
+	err := ParseSeqAnnotationDataFilePathStream(filePath, "", "", "", Options{}, func(int, model.SequenceAnnotation) error {
+		return handlerErr
+	})
+	assert.True(t, errors.Is(err, handlerErr))
+	assert.False(t, errors.Is(err, ErrMalformedTable))
+}
@@ -0,0 +1,133 @@
+package parse
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	breseqHtmlFileName = "index.html"
+	gdFileExt          = ".gd"
+	globMetaChars      = "*?["
+
+	errNoSuchPathMsgFmt        = "No such file or directory: '%s'"
+	errNoGlobMatchesMsgFmt     = "No files match the pattern: '%s'"
+	errMalformedGlobMsgFmt     = "Malformed pattern: '%s' Error: '%s'"
+	errNoSeqAnnotationFilesFmt = "No %s or *%s files found in the directory: '%s'"
+)
+
+// FindSeqAnnotationFiles expands the paths into the sequence annotation files they name.
+// Each path is either a file, a directory searched recursively for breseq's index.html
+// and GenomeDiff *.gd files, or a glob pattern of files and directories. breseq writes
+// the same mutations to both output/index.html and output/output.gd, so a directory's
+// *.gd files are skipped when it has an index.html. Files are returned in the order
+// given, with directories in lexical order, and each file only once.
+//
+// Paths that don't name any files, e.g. a missing file, a pattern matching nothing, or a
+// directory without sequence annotation files, are returned as errors in the order given,
+// rather than stopping the rest from being found, so each can be reported as a failed
+// file. Missing files and patterns matching nothing match os.ErrNotExist with errors.Is.
+func FindSeqAnnotationFiles(paths []string) ([]string, []error) {
+	files, errs := []string{}, []error{}
+	seen := map[string]bool{}
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, path := range paths {
+		matches, err := findPathSeqAnnotationFiles(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	return files, errs
+}
+
+// findPathSeqAnnotationFiles expands a single path the same as FindSeqAnnotationFiles.
+func findPathSeqAnnotationFiles(path string) ([]string, error) {
+	matches := []string{path}
+	if strings.ContainsAny(path, globMetaChars) {
+		var err error
+		matches, err = filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf(errMalformedGlobMsgFmt, path, err.Error())
+		}
+
+		if len(matches) <= 0 {
+			return nil, newSentinelError(os.ErrNotExist, fmt.Errorf(errNoGlobMatchesMsgFmt, path))
+		}
+	}
+
+	files := []string{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if os.IsNotExist(err) {
+			return nil, newSentinelError(os.ErrNotExist, fmt.Errorf(errNoSuchPathMsgFmt, match))
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+
+		dirFiles, err := findDirSeqAnnotationFiles(match)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// findDirSeqAnnotationFiles walks the directory for index.html and *.gd files.
+func findDirSeqAnnotationFiles(dir string) ([]string, error) {
+	htmlDirs := map[string]bool{}
+	candidates := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		name := strings.ToLower(d.Name())
+		if name == breseqHtmlFileName {
+			htmlDirs[filepath.Dir(path)] = true
+			candidates = append(candidates, path)
+		} else if filepath.Ext(name) == gdFileExt {
+			candidates = append(candidates, path)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, path := range candidates {
+		if filepath.Ext(strings.ToLower(path)) == gdFileExt && htmlDirs[filepath.Dir(path)] {
+			continue
+		}
+		files = append(files, path)
+	}
+
+	if len(files) <= 0 {
+		return nil, fmt.Errorf(errNoSeqAnnotationFilesFmt, breseqHtmlFileName, gdFileExt, dir)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
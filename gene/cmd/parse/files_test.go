@@ -0,0 +1,99 @@
+package parse
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0600))
+	}
+}
+
+func TestFindSeqAnnotationFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir,
+		"ara-1/500/output/index.html",
+		"ara-1/500/output/output.gd",
+		"ara-1/1000/output/index.html",
+		"ara-1/1000/output/evidence/notes.txt",
+		"ara+1/2000.gd",
+		"ara+1/2000.html",
+		"ara+1/1500.gd",
+	)
+
+	files, errs := FindSeqAnnotationFiles([]string{filepath.Join(dir, "ara-1")})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "ara-1/1000/output/index.html"),
+		filepath.Join(dir, "ara-1/500/output/index.html"),
+	}, files)
+
+	files, errs = FindSeqAnnotationFiles([]string{filepath.Join(dir, "ara+1")})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{filepath.Join(dir, "ara+1/1500.gd"), filepath.Join(dir, "ara+1/2000.gd")}, files)
+
+	// Files are kept in the order given, and only once.
+	files, errs = FindSeqAnnotationFiles([]string{
+		filepath.Join(dir, "ara+1/2000.html"),
+		filepath.Join(dir, "ara+1/*.gd"),
+		filepath.Join(dir, "ara+1/2000.gd"),
+	})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "ara+1/2000.html"),
+		filepath.Join(dir, "ara+1/1500.gd"),
+		filepath.Join(dir, "ara+1/2000.gd"),
+	}, files)
+
+	// Patterns can match directories.
+	files, errs = FindSeqAnnotationFiles([]string{filepath.Join(dir, "ara-1/*")})
+	assert.Empty(t, errs)
+	assert.Len(t, files, 2)
+}
+
+func TestFindSeqAnnotationFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "empty/notes.txt", "ara-1/500.gd", "ara-1/1000.gd")
+
+	cases := []struct {
+		name     string
+		path     string
+		notExist bool
+	}{
+		{"Missing File", filepath.Join(dir, "missing.gd"), true},
+		{"No Glob Matches", filepath.Join(dir, "*.gd"), true},
+		{"Malformed Glob", filepath.Join(dir, "[.gd"), false},
+		{"No Files In Directory", filepath.Join(dir, "empty"), false},
+	}
+
+	for _, c := range cases {
+		files, errs := FindSeqAnnotationFiles([]string{c.path})
+		assert.Empty(t, files, c.name)
+		assert.Len(t, errs, 1, c.name)
+		if len(errs) == 1 {
+			assert.Contains(t, errs[0].Error(), c.path, c.name)
+			assert.Equal(t, c.notExist, errors.Is(errs[0], os.ErrNotExist), c.name)
+		}
+	}
+
+	// The paths naming files are still found, around the ones that don't.
+	paths := []string{filepath.Join(dir, "ara-1/500.gd")}
+	for _, c := range cases {
+		paths = append(paths, c.path)
+	}
+	paths = append(paths, filepath.Join(dir, "ara-1/1000.gd"))
+	files, errs := FindSeqAnnotationFiles(paths)
+	assert.Equal(t, []string{filepath.Join(dir, "ara-1/500.gd"), filepath.Join(dir, "ara-1/1000.gd")}, files)
+	assert.Len(t, errs, len(cases))
+	for i, err := range errs {
+		assert.Contains(t, err.Error(), cases[i].path, cases[i].name)
+	}
+}
@@ -0,0 +1,70 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"regexp"
+	"strings"
+)
+
+const (
+	forwardStrand = "+"
+	reverseStrand = "-"
+)
+
+var (
+	// strandedGeneRegex matches a gene followed by its strand arrow, e.g. 'abcA →'.
+	strandedGeneRegex = regexp.MustCompile(`^(\S+) ?([←→])$`)
+	// strandedRightGeneRegex matches a strand arrow followed by its gene, e.g. '→ abcC',
+	// as on the right side of an intergenic mutation.
+	strandedRightGeneRegex = regexp.MustCompile(`^([←→]) ?(\S+)$`)
+	// geneRangeRegex matches a range of genes, e.g. '[abcA]–[abcD]', where the brackets
+	// mark the genes only partially affected.
+	geneRangeRegex = regexp.MustCompile(`^\[?([^\[\]\s–]+)\]?–\[?([^\[\]\s–]+)\]?$`)
+
+	geneArrowStrands = map[string]string{
+		string(rightGeneArrow): forwardStrand,
+		string(leftGeneArrow):  reverseStrand,
+	}
+
+	// geneReplacer normalizes the non-breaking spaces breseq renders in its HTML output.
+	geneReplacer = strings.NewReplacer("\u00A0", " ")
+)
+
+// parseGeneContext decomposes the gene column into the strand of the gene a mutation
+// is in, e.g. 'abcA →', the flanking genes of an intergenic mutation, e.g.
+// 'abcB ← / → abcC', or the range of genes of a multi-gene mutation, e.g.
+// '[abcA]–[abcD]'. Gene columns of other forms, like lists, have no context.
+func parseGeneContext(gene string) model.GeneContext {
+	normalized := strings.TrimSpace(geneReplacer.Replace(gene))
+	if sides := strings.Split(normalized, string(intergenicSeparator)); len(sides) == 2 {
+		context := model.GeneContext{}
+		left, right := strings.TrimSpace(sides[0]), strings.TrimSpace(sides[1])
+		if m := strandedGeneRegex.FindStringSubmatch(left); m != nil {
+			context.LeftGene, context.LeftStrand = m[1], geneArrowStrands[m[2]]
+		} else if !isNoGene(left) {
+			return model.GeneContext{}
+		}
+
+		if m := strandedRightGeneRegex.FindStringSubmatch(right); m != nil {
+			context.RightGene, context.RightStrand = m[2], geneArrowStrands[m[1]]
+		} else if !isNoGene(right) {
+			return model.GeneContext{}
+		}
+		return context
+	}
+
+	if m := geneRangeRegex.FindStringSubmatch(normalized); m != nil {
+		return model.GeneContext{FirstGene: m[1], LastGene: m[2]}
+	}
+
+	if m := strandedGeneRegex.FindStringSubmatch(normalized); m != nil {
+		return model.GeneContext{Strand: geneArrowStrands[m[2]]}
+	}
+	return model.GeneContext{}
+}
+
+// isNoGene checks whether the side of an intergenic gene column is a dash, shown
+// when there's no gene on that side.
+func isNoGene(side string) bool {
+	return side == string(geneRangeSeparator) || side == noGeneName
+}
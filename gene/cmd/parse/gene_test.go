@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseGeneContext(t *testing.T) {
+	cases := []struct {
+		gene     string
+		expected model.GeneContext
+	}{
+		{"abcA\u00A0→", model.GeneContext{Strand: "+"}},
+		{"abcA ←", model.GeneContext{Strand: "-"}},
+		{"abcB\u00A0←\u00A0/\u00A0→\u00A0abcC", model.GeneContext{LeftGene: "abcB", LeftStrand: "-", RightGene: "abcC", RightStrand: "+"}},
+		{"abcB → / ← abcC", model.GeneContext{LeftGene: "abcB", LeftStrand: "+", RightGene: "abcC", RightStrand: "-"}},
+		{"– / → abcC", model.GeneContext{RightGene: "abcC", RightStrand: "+"}},
+		{"abcB ← / –", model.GeneContext{LeftGene: "abcB", LeftStrand: "-"}},
+		{"[abcA]–[abcD]", model.GeneContext{FirstGene: "abcA", LastGene: "abcD"}},
+		{"abcA–[abcD]", model.GeneContext{FirstGene: "abcA", LastGene: "abcD"}},
+		{"", model.GeneContext{}},
+		{"abcA", model.GeneContext{}},
+		{"[abcA], abcB, [abcC]", model.GeneContext{}},
+		{"abcB / abcC", model.GeneContext{}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, parseGeneContext(c.gene), c.gene)
+	}
+}
@@ -0,0 +1,439 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const (
+	gdFileType          fileType = "gd"
+	gdHeaderPrefix               = "#=GENOME_DIFF"
+	gdMetadataPrefix             = "#="
+	gdCommentPrefix              = "#"
+	gdFieldSeparator             = "\t"
+	gdKeyValueSeparator          = "="
+	gdListSeparator              = ","
+	gdMinEntryFields             = 3
+	gdMaxLineSize                = 1024 * 1024
+	gdDefaultFrequency           = 1.0
+	gdEmptyParentId              = "."
+
+	errMissingGdHeaderMsg         = "GenomeDiff file is missing the '#=GENOME_DIFF' header line"
+	errBreseqGdVersMismatchMsgFmt = "Expected a breseq %s.* GenomeDiff file, but got version: '%s'"
+	errMalformedGdLineMsgFmt      = "Malformed GenomeDiff entry on line %d. Error: '%s'"
+	errNotEnoughGdFields          = "Not enough fields"
+	errMissingGdFieldsMsgFmt      = "Expected %d type-specific fields for '%s', but got %d"
+	errInvalidGdKeyValueFmt       = "Invalid key=value field: '%s'"
+	errInvalidGdPositionFmt       = "Invalid position: '%s'"
+	errInvalidGdFrequencyFmt      = "Invalid frequency: '%s'"
+	errUnknownGdMutationFmt       = "Unknown mutation type: '%s'"
+	errInvalidGdStrandFmt         = "Invalid strand: '%s'"
+	validGdHeaderMsg              = "Valid GenomeDiff Header"
+	skippingGdEntryTypeMsgFmt     = "Skipping GenomeDiff entry of type: '%s'\n"
+)
+
+// gdEntry is a single mutation or evidence line of a GenomeDiff file. The
+// type-specific positional fields are stored alongside the trailing key=value
+// fields, keyed by their names in the GenomeDiff specification.
+//
+// See for more details: https://barricklab.org/twiki/pub/Lab/ToolsBacterialGenomeResequencing/documentation/gd_format.html
+type gdEntry struct {
+	entryType string
+	id        string
+	parentIds []string
+	fields    map[string]string
+	line      int
+}
+
+// gdFile is the parsed content of a GenomeDiff file.
+type gdFile struct {
+	metadata  map[string]string
+	mutations []*gdEntry
+	evidence  map[string]*gdEntry
+}
+
+var (
+	// gdMutationSpecs maps each mutation type to its positional field names.
+	gdMutationSpecs = map[string][]string{
+		"SNP": {"seq_id", "position", "new_seq"},
+		"SUB": {"seq_id", "position", "size", "new_seq"},
+		"DEL": {"seq_id", "position", "size"},
+		"INS": {"seq_id", "position", "new_seq"},
+		"MOB": {"seq_id", "position", "repeat_name", "strand", "duplication_size"},
+		"AMP": {"seq_id", "position", "size", "new_copy_number"},
+		"CON": {"seq_id", "position", "size", "region"},
+		"INV": {"seq_id", "position", "size"},
+	}
+
+	// gdEvidenceSpecs maps each evidence type to its positional field names.
+	gdEvidenceSpecs = map[string][]string{
+		"RA": {"seq_id", "position", "insert_position", "ref_base", "new_base"},
+		"MC": {"seq_id", "start", "end", "start_range", "end_range"},
+		"JC": {"side_1_seq_id", "side_1_position", "side_1_strand", "side_2_seq_id", "side_2_position", "side_2_strand", "overlap"},
+		"UN": {"seq_id", "start", "end"},
+	}
+)
+
+// parseBreseqGdFile reads a breseq GenomeDiff file and converts each of its mutation
+// lines into a sequence annotation. Evidence lines are used to fill in details
+// missing from the mutation lines, like the reference base of a SNP.
+//
+// Returns a single collection holding all of the mutations in file order.
+func parseBreseqGdFile(reader io.Reader, version string) ([][]model.SequenceAnnotation, error) {
+	gd, err := parseGdEntries(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files without the '#=PROGRAM' metadata are taken to be the version.
+	if matches := breseqProgramPattern.FindStringSubmatch(gd.metadata[gdProgramKey]); matches != nil {
+		if vers := normalizeAppVersion(matches[1]); vers != version {
+			return nil, newSentinelError(ErrVersionMismatch, fmt.Errorf(errBreseqGdVersMismatchMsgFmt, version, vers))
+		}
+	}
+
+	results := []model.SequenceAnnotation{}
+	for _, mutation := range gd.mutations {
+		sa, err := changeGdMutationToSeqAnnotation(mutation, gd.evidence, version)
+		if err != nil {
+			return nil, newGdLineError(mutation.line, err)
+		}
+		results = append(results, sa)
+	}
+	return [][]model.SequenceAnnotation{results}, nil
+}
+
+// newGdLineError reports a malformed GenomeDiff entry on the line.
+func newGdLineError(lineNum int, err error) error {
+	return &ParseError{Line: lineNum, Err: newSentinelError(ErrMalformedTable, fmt.Errorf(errMalformedGdLineMsgFmt, lineNum, err.Error()))}
+}
+
+// parseGdEntries tokenizes the GenomeDiff lines into mutation and evidence entries.
+// Unrecognized entry types, e.g. validation entries like TSEQ or NOTE, are skipped.
+func parseGdEntries(reader io.Reader) (*gdFile, error) {
+	gd := &gdFile{
+		metadata: map[string]string{},
+		evidence: map[string]*gdEntry{},
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), gdMaxLineSize)
+	hasHeader := false
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !hasHeader {
+			if !strings.HasPrefix(line, gdHeaderPrefix) {
+				return nil, newSentinelError(ErrUnsupportedFormat, errors.New(errMissingGdHeaderMsg))
+			}
+			log.Println(validGdHeaderMsg)
+			hasHeader = true
+		}
+
+		if strings.HasPrefix(line, gdMetadataPrefix) {
+			key, value := splitGdMetadata(line)
+			gd.metadata[key] = value
+			continue
+		}
+
+		if strings.HasPrefix(line, gdCommentPrefix) {
+			continue
+		}
+
+		entry, err := parseGdEntry(line, lineNum)
+		if err != nil {
+			return nil, newGdLineError(lineNum, err)
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		if _, isMutation := gdMutationSpecs[entry.entryType]; isMutation {
+			gd.mutations = append(gd.mutations, entry)
+		} else {
+			gd.evidence[entry.id] = entry
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !hasHeader {
+		return nil, newSentinelError(ErrUnsupportedFormat, errors.New(errMissingGdHeaderMsg))
+	}
+
+	return gd, nil
+}
+
+// splitGdMetadata splits a '#=KEY value' line into its key and value.
+func splitGdMetadata(line string) (string, string) {
+	content := strings.TrimPrefix(line, gdMetadataPrefix)
+	i := strings.IndexAny(content, emptyChar+gdFieldSeparator)
+	if i < 0 {
+		return strings.TrimSpace(content), ""
+	}
+	return strings.TrimSpace(content[:i]), strings.TrimSpace(content[i+1:])
+}
+
+// parseGdEntry parses a single tab-delimited entry line. Returns a nil entry
+// if the entry type is not a supported mutation or evidence type.
+func parseGdEntry(line string, lineNum int) (*gdEntry, error) {
+	cols := strings.Split(line, gdFieldSeparator)
+	if len(cols) < gdMinEntryFields {
+		return nil, errors.New(errNotEnoughGdFields)
+	}
+
+	entryType := cols[0]
+	spec, isMutation := gdMutationSpecs[entryType]
+	if !isMutation {
+		var isEvidence bool
+		spec, isEvidence = gdEvidenceSpecs[entryType]
+		if !isEvidence {
+			log.Printf(skippingGdEntryTypeMsgFmt, entryType)
+			return nil, nil
+		}
+	}
+
+	specCols := cols[gdMinEntryFields:]
+	if len(specCols) < len(spec) {
+		return nil, fmt.Errorf(errMissingGdFieldsMsgFmt, len(spec), entryType, len(specCols))
+	}
+
+	entry := &gdEntry{
+		entryType: entryType,
+		id:        cols[1],
+		fields:    map[string]string{},
+		line:      lineNum,
+	}
+
+	if cols[2] != gdEmptyParentId && cols[2] != "" {
+		entry.parentIds = strings.Split(cols[2], gdListSeparator)
+	}
+
+	for i, name := range spec {
+		entry.fields[name] = specCols[i]
+	}
+
+	for _, kv := range specCols[len(spec):] {
+		if kv == "" {
+			continue
+		}
+
+		parts := strings.SplitN(kv, gdKeyValueSeparator, 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf(errInvalidGdKeyValueFmt, kv)
+		}
+		// Positional fields take precedence over any repeated key=value fields.
+		if _, exists := entry.fields[parts[0]]; !exists {
+			entry.fields[parts[0]] = parts[1]
+		}
+	}
+
+	return entry, nil
+}
+
+// changeGdMutationToSeqAnnotation converts a mutation entry into the same display
+// representation breseq uses for its HTML output, so annotations parsed from
+// either format can be compared with each other.
+func changeGdMutationToSeqAnnotation(mutation *gdEntry, evidence map[string]*gdEntry, version string) (model.SequenceAnnotation, error) {
+	position, err := formatGdNumber(mutation.fields["position"])
+	if err != nil {
+		return model.SequenceAnnotation{}, fmt.Errorf(errInvalidGdPositionFmt, mutation.fields["position"])
+	}
+
+	mutationStr, err := formatGdMutation(mutation, evidence)
+	if err != nil {
+		return model.SequenceAnnotation{}, err
+	}
+
+	frequency, err := formatGdFrequency(mutation.fields["frequency"])
+	if err != nil {
+		return model.SequenceAnnotation{}, err
+	}
+
+	sa := model.SequenceAnnotation{
+		SequenceId:  mutation.fields["seq_id"],
+		Position:    position,
+		Mutation:    mutationStr,
+		Frequency:   frequency,
+		Annotation:  formatGdAnnotation(mutation.fields),
+		Gene:        formatGdGene(mutation.fields),
+		Description: mutation.fields["gene_product"],
+		Application: string(breseq),
+		AppVersion:  version,
+	}
+	if err = populateTypedFields(&sa); err != nil {
+		return model.SequenceAnnotation{}, err
+	}
+	return sa, nil
+}
+
+// formatGdMutation renders the mutation type-specific fields the way breseq
+// displays them in the mutation column, e.g. 'A→G', '+G' or 'Δ1,234 bp'.
+func formatGdMutation(mutation *gdEntry, evidence map[string]*gdEntry) (string, error) {
+	fields := mutation.fields
+	switch mutation.entryType {
+	case "SNP":
+		return findGdRefBase(mutation, evidence) + "→" + fields["new_seq"], nil
+	case "SUB":
+		size, err := formatGdNumber(fields["size"])
+		if err != nil {
+			return "", err
+		}
+		return size + " bp→" + fields["new_seq"], nil
+	case "DEL":
+		size, err := formatGdNumber(fields["size"])
+		if err != nil {
+			return "", err
+		}
+		return "Δ" + size + " bp", nil
+	case "INS":
+		return "+" + fields["new_seq"], nil
+	case "MOB":
+		strand, err := formatGdStrand(fields["strand"])
+		if err != nil {
+			return "", err
+		}
+
+		mob := fmt.Sprintf("%s (%s)", fields["repeat_name"], strand)
+		if dup, err := strconv.Atoi(fields["duplication_size"]); err == nil && dup > 0 {
+			mob = fmt.Sprintf("%s +%d bp", mob, dup)
+		}
+		return mob, nil
+	case "AMP":
+		size, err := formatGdNumber(fields["size"])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s bp x %s", size, fields["new_copy_number"]), nil
+	case "CON":
+		size, err := formatGdNumber(fields["size"])
+		if err != nil {
+			return "", err
+		}
+		return size + " bp→" + fields["region"], nil
+	case "INV":
+		size, err := formatGdNumber(fields["size"])
+		if err != nil {
+			return "", err
+		}
+		return size + " bp inversion", nil
+	}
+
+	return "", fmt.Errorf(errUnknownGdMutationFmt, mutation.entryType)
+}
+
+// findGdRefBase looks up the reference base of a SNP through its supporting
+// read alignment evidence, falling back to the annotated ref_seq field.
+func findGdRefBase(mutation *gdEntry, evidence map[string]*gdEntry) string {
+	for _, parentId := range mutation.parentIds {
+		if ev, ok := evidence[parentId]; ok && ev.entryType == "RA" && ev.fields["ref_base"] != "" {
+			return ev.fields["ref_base"]
+		}
+	}
+
+	return mutation.fields["ref_seq"]
+}
+
+// formatGdAnnotation renders the amino acid change for coding mutations, e.g.
+// 'V12A (GTG→GGG)', otherwise the gene position, e.g. 'intergenic (-123/+12)'.
+func formatGdAnnotation(fields map[string]string) string {
+	aaRef, aaPos, aaNew := fields["aa_ref_seq"], fields["aa_position"], fields["aa_new_seq"]
+	if aaRef == "" || aaPos == "" || aaNew == "" {
+		return fields["gene_position"]
+	}
+
+	annotation := aaRef + aaPos + aaNew
+	if fields["codon_ref_seq"] != "" && fields["codon_new_seq"] != "" {
+		annotation = fmt.Sprintf("%s (%s→%s)", annotation, fields["codon_ref_seq"], fields["codon_new_seq"])
+	}
+	return annotation
+}
+
+// formatGdGene renders the gene names with their strand arrows, e.g.
+// 'abcA ← / → abcB' for intergenic mutations, matching the HTML output.
+func formatGdGene(fields map[string]string) string {
+	geneName := fields["gene_name"]
+	genes := strings.Split(geneName, "/")
+	strands := strings.Split(fields["gene_strand"], "/")
+	if fields["gene_strand"] == "" || len(genes) != len(strands) || len(genes) > 2 {
+		return geneName
+	}
+
+	arrows := make([]string, len(strands))
+	for i, strand := range strands {
+		switch strand {
+		case "<":
+			arrows[i] = "←"
+		case ">":
+			arrows[i] = "→"
+		default:
+			return geneName
+		}
+	}
+
+	if len(genes) == 1 {
+		return genes[0] + nonBreakingSpaceUnicode + arrows[0]
+	}
+	return genes[0] + nonBreakingSpaceUnicode + arrows[0] + nonBreakingSpaceUnicode + "/" +
+		nonBreakingSpaceUnicode + arrows[1] + nonBreakingSpaceUnicode + genes[1]
+}
+
+// formatGdFrequency renders a [0, 1] frequency as a percentage the same way as
+// breseq's HTML output, i.e. '100%' or '6.0%'. A missing frequency means the
+// mutation was fixed in the population.
+func formatGdFrequency(frequency string) (string, error) {
+	freq := gdDefaultFrequency
+	if frequency != "" {
+		var err error
+		freq, err = strconv.ParseFloat(frequency, 64)
+		if err != nil || freq < 0 || freq > 1 {
+			return "", fmt.Errorf(errInvalidGdFrequencyFmt, frequency)
+		}
+	}
+
+	if freq == gdDefaultFrequency {
+		return "100%", nil
+	}
+	return fmt.Sprintf("%.1f%%", freq*100), nil
+}
+
+func formatGdStrand(strand string) (string, error) {
+	switch strand {
+	case "1", "+1":
+		return "+", nil
+	case "-1":
+		return "-", nil
+	}
+	return "", fmt.Errorf(errInvalidGdStrandFmt, strand)
+}
+
+// formatGdNumber adds thousands separators to an integer, e.g. '12345' to '12,345'.
+func formatGdNumber(number string) (string, error) {
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	digits := strconv.FormatUint(n, 10)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,213 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const (
+	validBreseqGd = "#=GENOME_DIFF\t1.0\n" +
+		"#=AUTHOR\ttester\n" +
+		"#=REFSEQ\tBSeqs/REL606.gbk\n" +
+		"# A comment line\n" +
+		"SNP\t1\t9\tREL606\t12345\tG\tfrequency=0.06\taa_new_seq=A\taa_position=12\taa_ref_seq=V\tcodon_new_seq=GGG\tcodon_ref_seq=GTG\tgene_name=abcA\tgene_product=hypothetical protein\tgene_strand=>\n" +
+		"INS\t2\t10\tREL606\t65431\tG\tgene_name=abcB/abcC\tgene_position=intergenic (-123/+12)\tgene_product=lipoprotein, putative\tgene_strand=</>\n" +
+		"DEL\t3\t11,12\tREL606\t1000\t1234\tgene_name=[abcD]–[abcE]\n" +
+		"SUB\t4\t.\tREL606\t2000\t2\tAT\n" +
+		"MOB\t5\t13\tREL606\t3000\tIS150\t-1\t3\n" +
+		"AMP\t6\t.\tREL606\t4000\t1200\t2\n" +
+		"CON\t7\t.\tREL606\t5000\t100\tREL606:8000-8099\n" +
+		"INV\t8\t.\tREL606\t6000\t500\n" +
+		"RA\t9\t.\tREL606\t12345\t0\tT\tG\tfrequency=0.06\n" +
+		"RA\t10\t.\tREL606\t65431\t1\t.\tG\n" +
+		"MC\t11\t.\tREL606\t1000\t2233\t0\t0\n" +
+		"UN\t12\t.\tREL606\t1000\t2233\n" +
+		"JC\t13\t.\tREL606\t3000\t1\tREL606\t3003\t-1\t0\n" +
+		"TSEQ\t14\t.\tREL606\t1\t100\n"
+)
+
+func TestParseBreseqGdFile(t *testing.T) {
+	testResults, testErr := parseBreseqGdFile(strings.NewReader(validBreseqGd), string(breseqVers027Number))
+	assert.Nil(t, testErr)
+	assert.Equal(t, 1, len(testResults))
+	assert.Equal(t, 8, len(testResults[0]))
+
+	cases := []struct {
+		name        string
+		position    string
+		mutation    string
+		frequency   string
+		annotation  string
+		gene        string
+		description string
+	}{
+		{"SNP", "12,345", "T→G", "6.0%", "V12A (GTG→GGG)", "abcA\u00A0→", "hypothetical protein"},
+		{"INS", "65,431", "+G", "100%", "intergenic (-123/+12)", "abcB\u00A0←\u00A0/\u00A0→\u00A0abcC", "lipoprotein, putative"},
+		{"DEL", "1,000", "Δ1,234 bp", "100%", "", "[abcD]–[abcE]", ""},
+		{"SUB", "2,000", "2 bp→AT", "100%", "", "", ""},
+		{"MOB", "3,000", "IS150 (-) +3 bp", "100%", "", "", ""},
+		{"AMP", "4,000", "1,200 bp x 2", "100%", "", "", ""},
+		{"CON", "5,000", "100 bp→REL606:8000-8099", "100%", "", "", ""},
+		{"INV", "6,000", "500 bp inversion", "100%", "", "", ""},
+	}
+
+	for i, c := range cases {
+		sa := testResults[0][i]
+		assert.Equal(t, "REL606", sa.SequenceId, c.name)
+		assert.Equal(t, c.position, sa.Position, c.name)
+		assert.Equal(t, c.mutation, sa.Mutation, c.name)
+		assert.Equal(t, c.frequency, sa.Frequency, c.name)
+		assert.Equal(t, c.annotation, sa.Annotation, c.name)
+		assert.Equal(t, c.gene, sa.Gene, c.name)
+		assert.Equal(t, c.description, sa.Description, c.name)
+		assert.Equal(t, string(breseq), sa.Application, c.name)
+		assert.Equal(t, string(breseqVers027Number), sa.AppVersion, c.name)
+	}
+
+	assert.Equal(t, int64(12345), testResults[0][0].PositionValue)
+	assert.InDelta(t, 0.06, testResults[0][0].FrequencyValue, 1e-9)
+	assert.Equal(t, []string{"abcB", "abcC"}, testResults[0][1].Genes)
+	assert.Equal(t, 1.0, testResults[0][1].FrequencyValue)
+
+	mutationTypes := []model.MutationType{model.SnpMutation, model.InsertionMutation, model.DeletionMutation,
+		model.SubstitutionMutation, model.MobileElementMutation, model.AmplificationMutation,
+		model.SubstitutionMutation, model.InversionMutation}
+	for i, mutationType := range mutationTypes {
+		assert.Equal(t, mutationType, testResults[0][i].Variant.Type, cases[i].name)
+	}
+
+	assert.Equal(t, model.Effect{Category: model.MissenseEffect, AminoAcidRef: "V", AminoAcidPosition: 12,
+		AminoAcidAlt: "A", CodonRef: "GTG", CodonAlt: "GGG"}, testResults[0][0].Effect)
+	assert.Equal(t, model.Effect{Category: model.IntergenicEffect, LeftGeneDistance: -123, RightGeneDistance: 12},
+		testResults[0][1].Effect)
+
+	assert.Equal(t, model.GeneContext{Strand: "+"}, testResults[0][0].GeneContext)
+	assert.Equal(t, model.GeneContext{LeftGene: "abcB", LeftStrand: "-", RightGene: "abcC", RightStrand: "+"},
+		testResults[0][1].GeneContext)
+	assert.Equal(t, model.GeneContext{FirstGene: "abcD", LastGene: "abcE"}, testResults[0][2].GeneContext)
+}
+
+func TestParseSeqAnnotationDataGd(t *testing.T) {
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "GD", "breseq", "0.27", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, 1, len(testResults))
+	assert.Equal(t, 8, len(testResults[0]))
+
+	rerunResults, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseqGd), "GD", "breseq", "0.27", Options{})
+	assert.Nil(t, testErr)
+	uniqueIds := map[string]bool{}
+	for i, sa := range testResults[0] {
+		assert.Equal(t, sa.ContentId(), sa.UniqueId)
+		assert.Equal(t, rerunResults[0][i].UniqueId, sa.UniqueId)
+		uniqueIds[sa.UniqueId] = true
+	}
+	assert.Equal(t, 8, len(uniqueIds))
+}
+
+func TestParseGdEntries(t *testing.T) {
+	gd, testErr := parseGdEntries(strings.NewReader(validBreseqGd))
+	assert.Nil(t, testErr)
+	assert.Equal(t, "1.0", gd.metadata["GENOME_DIFF"])
+	assert.Equal(t, "tester", gd.metadata["AUTHOR"])
+	assert.Equal(t, 8, len(gd.mutations))
+	assert.Equal(t, 5, len(gd.evidence))
+
+	del := gd.mutations[2]
+	assert.Equal(t, "DEL", del.entryType)
+	assert.Equal(t, "3", del.id)
+	assert.Equal(t, []string{"11", "12"}, del.parentIds)
+	assert.Equal(t, "1234", del.fields["size"])
+	assert.Equal(t, 7, del.line)
+
+	jc := gd.evidence["13"]
+	assert.Equal(t, "JC", jc.entryType)
+	assert.Nil(t, jc.parentIds)
+	assert.Equal(t, "-1", jc.fields["side_2_strand"])
+	assert.Equal(t, "0", jc.fields["overlap"])
+}
+
+func TestParseBreseqGdFileInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		testGd string
+	}{
+		{
+			name:   "Empty",
+			testGd: "",
+		},
+		{
+			name:   "Missing Header",
+			testGd: "SNP\t1\t.\tREL606\t12345\tG\n",
+		},
+		{
+			name:   "Not Enough Fields",
+			testGd: "#=GENOME_DIFF\t1.0\nSNP\t1\n",
+		},
+		{
+			name:   "Missing Type-Specific Fields",
+			testGd: "#=GENOME_DIFF\t1.0\nDEL\t1\t.\tREL606\t12345\n",
+		},
+		{
+			name:   "Invalid Key Value",
+			testGd: "#=GENOME_DIFF\t1.0\nINS\t1\t.\tREL606\t12345\tG\tfrequency\n",
+		},
+		{
+			name:   "Invalid Position",
+			testGd: "#=GENOME_DIFF\t1.0\nINS\t1\t.\tREL606\t12a45\tG\n",
+		},
+		{
+			name:   "Invalid Frequency",
+			testGd: "#=GENOME_DIFF\t1.0\nINS\t1\t.\tREL606\t12345\tG\tfrequency=1.5\n",
+		},
+		{
+			name:   "Invalid Strand",
+			testGd: "#=GENOME_DIFF\t1.0\nMOB\t1\t.\tREL606\t12345\tIS150\t2\t3\n",
+		},
+	}
+
+	for _, c := range cases {
+		testResults, testErr := parseBreseqGdFile(strings.NewReader(c.testGd), string(breseqVers027Number))
+		assert.NotNil(t, testErr, c.name)
+		assert.Nil(t, testResults, c.name)
+	}
+}
+
+func TestFormatGdFrequency(t *testing.T) {
+	cases := []struct {
+		frequency string
+		expected  string
+	}{
+		{"", "100%"},
+		{"1", "100%"},
+		{"0.06", "6.0%"},
+		{"0.1234", "12.3%"},
+		{"0", "0.0%"},
+	}
+
+	for _, c := range cases {
+		frequency, err := formatGdFrequency(c.frequency)
+		assert.Nil(t, err, c.frequency)
+		assert.Equal(t, c.expected, frequency, c.frequency)
+	}
+}
+
+func TestFormatGdNumber(t *testing.T) {
+	cases := []struct {
+		number   string
+		expected string
+	}{
+		{"1", "1"},
+		{"123", "123"},
+		{"1234", "1,234"},
+		{"123456", "123,456"},
+		{"1234567", "1,234,567"},
+	}
+
+	for _, c := range cases {
+		number, err := formatGdNumber(c.number)
+		assert.Nil(t, err, c.number)
+		assert.Equal(t, c.expected, number, c.number)
+	}
+}
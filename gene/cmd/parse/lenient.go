@@ -0,0 +1,187 @@
+package parse
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"log"
+)
+
+const (
+	warnImpliedEndTagMsgFmt   = "Repaired an unclosed tag. Implied: '</%s>' before: '%s'"
+	warnImpliedStartTagMsgFmt = "Repaired a missing tag. Implied: '<%s>' before: '%s'"
+	warnStrayTagMsgFmt        = "Skipped a stray tag: '%s'"
+	endOfFileTag              = "end of file"
+)
+
+// lenientTag is a token handed out by the lenientTokenizer, which is either read
+// from the html or implied by a repair.
+type lenientTag struct {
+	token   html.Token
+	implied bool
+}
+
+// lenientTokenizer repairs malformed tables the way browsers do, following the HTML5
+// rules for implicitly closing table tags, before the tokens reach the parser. A <td>
+// or <th> closes the column left open before it, and implies a <tr> when it's directly
+// inside a table. A <tr> closes the column and row left open before it, and a <table>
+// directly inside another table or row closes that table first. An end tag closes the
+// tags left open inside of it, up to the nearest table, and is skipped if there's no
+// open tag for it. Tags outside of a table are skipped, and those left open at the end
+// of the file are closed.
+//
+// Each repair is handed to onWarning as a *ParseError located at the token that
+// triggered it, and matching ErrMalformedTable with errors.Is. Problems the rules
+// don't cover, e.g. rows in a table nested inside another row, are still errors.
+type lenientTokenizer struct {
+	tk        *persistedTokenizer
+	onWarning WarningHandler
+	// open holds the table, row, and column tags yet to be closed, innermost last.
+	open []atom.Atom
+	// queued holds the tags to hand out before reading the next token.
+	queued  []lenientTag
+	current lenientTag
+}
+
+func newLenientTokenizer(tk *persistedTokenizer, onWarning WarningHandler) *lenientTokenizer {
+	return &lenientTokenizer{
+		tk:        tk,
+		onWarning: onWarning,
+	}
+}
+
+func (s *lenientTokenizer) Next() html.TokenType {
+	for len(s.queued) <= 0 {
+		s.tk.Next()
+		s.repair(s.tk.Token())
+	}
+
+	s.current, s.queued = s.queued[0], s.queued[1:]
+	return s.current.token.Type
+}
+
+func (s *lenientTokenizer) Token() html.Token {
+	return s.current.token
+}
+
+func (s *lenientTokenizer) Err() error {
+	return s.tk.Err()
+}
+
+// Raw returns the raw html of the current token, which is empty if it's implied.
+func (s *lenientTokenizer) Raw() []byte {
+	if s.current.implied {
+		return nil
+	}
+	return s.tk.Raw()
+}
+
+// repair queues the token read, along with any tags implied before it.
+func (s *lenientTokenizer) repair(token html.Token) {
+	switch {
+	case token.Type == html.StartTagToken && isTableTag(token.DataAtom):
+		if !s.repairStartTag(token) {
+			return
+		}
+		s.open = append(s.open, token.DataAtom)
+	case token.Type == html.EndTagToken && isTableTag(token.DataAtom):
+		depth := s.openDepth(token.DataAtom)
+		if depth < 0 {
+			s.warn(fmt.Errorf(warnStrayTagMsgFmt, tagName(token)))
+			return
+		}
+
+		for len(s.open) > depth+1 {
+			s.closeImplied(tagName(token))
+		}
+		s.open = s.open[:depth]
+	case token.Type == html.ErrorToken && s.tk.Err() == io.EOF:
+		for len(s.open) > 0 {
+			s.closeImplied(endOfFileTag)
+		}
+	}
+
+	s.queued = append(s.queued, lenientTag{token: token})
+}
+
+// repairStartTag closes the tags the start tag implicitly closes, and implies a row
+// for a column directly inside a table. Returns false if the tag is skipped instead.
+func (s *lenientTokenizer) repairStartTag(token html.Token) bool {
+	if token.DataAtom == atom.Table {
+		if top := s.top(); top == atom.Table || top == atom.Tr {
+			for s.top() != atom.Table {
+				s.closeImplied(tagName(token))
+			}
+			s.closeImplied(tagName(token))
+		}
+		return true
+	}
+
+	if len(s.open) <= 0 {
+		s.warn(fmt.Errorf(warnStrayTagMsgFmt, tagName(token)))
+		return false
+	}
+
+	if top := s.top(); top == atom.Td || top == atom.Th {
+		s.closeImplied(tagName(token))
+	}
+
+	switch {
+	case token.DataAtom == atom.Tr && s.top() == atom.Tr:
+		s.closeImplied(tagName(token))
+	case token.DataAtom != atom.Tr && s.top() == atom.Table:
+		s.warn(fmt.Errorf(warnImpliedStartTagMsgFmt, atom.Tr, tagName(token)))
+		s.queued = append(s.queued, lenientTag{html.Token{Type: html.StartTagToken, DataAtom: atom.Tr, Data: atom.Tr.String()}, true})
+		s.open = append(s.open, atom.Tr)
+	}
+	return true
+}
+
+// closeImplied queues the end tag of the innermost open tag, implied before the tag given.
+func (s *lenientTokenizer) closeImplied(before string) {
+	tag := s.top()
+	s.warn(fmt.Errorf(warnImpliedEndTagMsgFmt, tag, before))
+	s.queued = append(s.queued, lenientTag{html.Token{Type: html.EndTagToken, DataAtom: tag, Data: tag.String()}, true})
+	s.open = s.open[:len(s.open)-1]
+}
+
+// openDepth finds the innermost open tag matching the end tag's, looking no further
+// out than the nearest table. Returns -1 if there's none.
+func (s *lenientTokenizer) openDepth(tag atom.Atom) int {
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] == tag {
+			return i
+		} else if s.open[i] == atom.Table {
+			break
+		}
+	}
+	return -1
+}
+
+// top returns the innermost open tag, or 0 if there's none.
+func (s *lenientTokenizer) top() atom.Atom {
+	if len(s.open) <= 0 {
+		return 0
+	}
+	return s.open[len(s.open)-1]
+}
+
+func (s *lenientTokenizer) warn(err error) {
+	warning := s.tk.locate(newSentinelError(ErrMalformedTable, err))
+	log.Println(warning)
+	s.onWarning(warning)
+}
+
+// isTableTag reports whether the tag is one of the table, row, or column tags parsed.
+func isTableTag(tag atom.Atom) bool {
+	return tag == atom.Table || tag == atom.Tr || tag == atom.Td || tag == atom.Th
+}
+
+// tagName formats the tag of the token without its attributes, e.g. '<td>' or '</tr>'.
+func tagName(token html.Token) string {
+	if token.Type == html.EndTagToken {
+		return "</" + token.DataAtom.String() + ">"
+	}
+	return "<" + token.DataAtom.String() + ">"
+}
@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestLenientTokenizer(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected []table
+		warnings []string
+	}{
+		{"Well Formed", wellFormedHtmlTableString, []table{{{testVal + " " + itTestVal}}}, []string{}},
+		{"Unclosed Columns", "<table><tr><th>a<th>b</tr><tr><td>c<td>d</tr></table>", []table{{{"a", "b"}, {"c", "d"}}}, []string{
+			"Implied: '</th>' before: '<th>'",
+			"Implied: '</th>' before: '</tr>'",
+			"Implied: '</td>' before: '<td>'",
+			"Implied: '</td>' before: '</tr>'",
+		}},
+		{"Unclosed Rows", "<table><tr><td>a</td><tr><td>b</td></table>", []table{{{"a"}, {"b"}}}, []string{
+			"Implied: '</tr>' before: '<tr>'",
+			"Implied: '</tr>' before: '</table>'",
+		}},
+		{"Missing Row", "<table><td>a</td></table>", []table{{{"a"}}}, []string{
+			"Implied: '<tr>' before: '<td>'",
+			"Implied: '</tr>' before: '</table>'",
+		}},
+		{"Stray End Tags", "<table><tr><td>a</td></td></tr></tr></table></table>", []table{{{"a"}}}, []string{
+			"Skipped a stray tag: '</td>'",
+			"Skipped a stray tag: '</tr>'",
+			"Skipped a stray tag: '</table>'",
+		}},
+		{"Column Outside Table", "<td>a</td><table><tr><td>b</td></tr></table>", []table{{{"b"}}}, []string{
+			"Skipped a stray tag: '<td>'",
+			"Skipped a stray tag: '</td>'",
+		}},
+		{"Table Inside Table", "<table><tr><td>a</td></tr><table><tr><td>b</td></tr></table>", []table{{{"a"}}, {{"b"}}}, []string{
+			"Implied: '</table>' before: '<table>'",
+		}},
+		{"Unclosed At End Of File", "<table><tr><td>a", []table{{{"a"}}}, []string{
+			"Implied: '</td>' before: 'end of file'",
+			"Implied: '</tr>' before: 'end of file'",
+			"Implied: '</table>' before: 'end of file'",
+		}},
+	}
+
+	for _, c := range cases {
+		warnings := []*ParseError{}
+		testTables, testErr := tokenizeDataTableHtml(strings.NewReader(c.data), nil, func(warning *ParseError) {
+			warnings = append(warnings, warning)
+		})
+		assert.Nil(t, testErr, c.name)
+		assert.Equal(t, c.expected, testTables, c.name)
+		assert.Len(t, warnings, len(c.warnings), c.name)
+		for i, warning := range warnings {
+			if i < len(c.warnings) {
+				assert.Contains(t, warning.Error(), c.warnings[i], c.name)
+			}
+			assert.True(t, errors.Is(warning, ErrMalformedTable), c.name)
+			assert.True(t, warning.Line > 0 && warning.Column > 0, c.name)
+		}
+
+		// Strict mode stops at the first problem, or drops the tables left open, instead.
+		if len(c.warnings) > 0 {
+			strictTables, _ := parseDataTableHtmlTokenizer(strings.NewReader(c.data))
+			assert.NotEqual(t, c.expected, strictTables, c.name)
+		}
+	}
+}
+
+func TestLenientTokenizerWarningPosition(t *testing.T) {
+	warnings := []*ParseError{}
+	_, testErr := tokenizeDataTableHtml(strings.NewReader("<table>\n<tr><td>a\n</tr></table>"), nil, func(warning *ParseError) {
+		warnings = append(warnings, warning)
+	})
+	assert.Nil(t, testErr)
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, 3, warnings[0].Line)
+	assert.Equal(t, 1, warnings[0].Column)
+	assert.Equal(t, int64(18), warnings[0].Offset)
+	assert.Equal(t, "<table>\n<tr><td>a\n</tr>", warnings[0].Snippet)
+}
+
+func TestLenientTokenizerUnrepairable(t *testing.T) {
+	// Rows inside a table nested in another row are never valid.
+	_, testErr := tokenizeDataTableHtml(strings.NewReader("<table><tr><td><table><tr><td>a</td></tr></table></td></tr></table>"), nil, func(*ParseError) {})
+	assert.True(t, errors.Is(testErr, ErrMalformedTable))
+}
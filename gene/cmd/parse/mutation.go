@@ -0,0 +1,105 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	mobileMinusStrand = "–"
+)
+
+// mutationPattern matches the display text of one type of mutation, and decomposes
+// the submatches into the variant's parts.
+type mutationPattern struct {
+	regex *regexp.Regexp
+	parse func(m []string) model.Variant
+}
+
+var (
+	// mutationPatterns are tried in order, so the SNP pattern must come before the
+	// more general substitution patterns. The display texts are those rendered by
+	// breseq, and by formatGdMutation for GenomeDiff files.
+	mutationPatterns = []mutationPattern{
+		// 'A→G', or '→G' when a GenomeDiff SNP is missing its reference base.
+		{regexp.MustCompile(`^([ACGTN]?)→([ACGTN])$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.SnpMutation, RefBases: m[1], AltBases: m[2], Length: 1}
+		}},
+		// '(TA)6→5'
+		{regexp.MustCompile(`^\(([ACGTN]+)\)(\d+)→(\d+)$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.RepeatMutation, RepeatUnit: m[1], RefCopies: atoi(m[2]), AltCopies: atoi(m[3])}
+		}},
+		// 'AC→TT'
+		{regexp.MustCompile(`^([ACGTN]{2,})→([ACGTN]+)$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.SubstitutionMutation, RefBases: m[1], AltBases: m[2], Length: int64(len(m[1]))}
+		}},
+		// '2 bp→AT', or '100 bp→REL606:8000-8099' when replaced by another region.
+		{regexp.MustCompile(`^([\d,]+) bp→(.+)$`), func(m []string) model.Variant {
+			v := model.Variant{Type: model.SubstitutionMutation, Length: parseBaseCount(m[1])}
+			if basesRegex.MatchString(m[2]) {
+				v.AltBases = m[2]
+			}
+			return v
+		}},
+		// '+GC'
+		{regexp.MustCompile(`^\+([ACGTN]+)$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.InsertionMutation, AltBases: m[1], Length: int64(len(m[1]))}
+		}},
+		// '+36 bp'
+		{regexp.MustCompile(`^\+([\d,]+) bp$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.InsertionMutation, Length: parseBaseCount(m[1])}
+		}},
+		// 'Δ1,234 bp'
+		{regexp.MustCompile(`^Δ([\d,]+) bp$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.DeletionMutation, Length: parseBaseCount(m[1])}
+		}},
+		// 'IS150 (+) +4 bp', 'IS150 (–)', or with trailing changes, e.g. 'IS1 (+) +9 bp :: Δ1 bp'.
+		{regexp.MustCompile(`^(\S+) \(([+\-–])\)(?: \+([\d,]+) bp)?(?:\s|$)`), func(m []string) model.Variant {
+			v := model.Variant{Type: model.MobileElementMutation, MobileElement: m[1], Strand: m[2]}
+			if v.Strand == mobileMinusStrand {
+				v.Strand = "-"
+			}
+			if m[3] != "" {
+				v.Duplication = parseBaseCount(m[3])
+			}
+			return v
+		}},
+		// '1,200 bp x 2', or '1,200 bp ×2'
+		{regexp.MustCompile(`^([\d,]+) bp ?[x×] ?(\d+)$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.AmplificationMutation, Length: parseBaseCount(m[1]), RefCopies: 1, AltCopies: atoi(m[2])}
+		}},
+		// '500 bp inversion'
+		{regexp.MustCompile(`^([\d,]+) bp inversion$`), func(m []string) model.Variant {
+			return model.Variant{Type: model.InversionMutation, Length: parseBaseCount(m[1])}
+		}},
+	}
+
+	basesRegex = regexp.MustCompile(`^[ACGTN]+$`)
+)
+
+// parseMutation decomposes the display text of a mutation into a typed variant.
+// Returns false with an empty variant if the mutation is of an unknown form, as
+// breseq's display text varies between versions, and the raw text is kept anyway.
+func parseMutation(mutation string) (model.Variant, bool) {
+	trimmed := strings.TrimSpace(mutation)
+	for _, pattern := range mutationPatterns {
+		if m := pattern.regex.FindStringSubmatch(trimmed); m != nil {
+			return pattern.parse(m), true
+		}
+	}
+	return model.Variant{}, false
+}
+
+// parseBaseCount parses a number of bases with thousands separators, e.g. '1,234'.
+// The patterns only match digits and commas, so there's no error to return.
+func parseBaseCount(count string) int64 {
+	n, _ := strconv.ParseInt(strings.Replace(count, positionSeparator, "", -1), 10, 64)
+	return n
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
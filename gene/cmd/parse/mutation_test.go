@@ -0,0 +1,43 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMutation(t *testing.T) {
+	cases := []struct {
+		mutation string
+		expected model.Variant
+	}{
+		{"A→G", model.Variant{Type: model.SnpMutation, RefBases: "A", AltBases: "G", Length: 1}},
+		{"→G", model.Variant{Type: model.SnpMutation, AltBases: "G", Length: 1}},
+		{"+G", model.Variant{Type: model.InsertionMutation, AltBases: "G", Length: 1}},
+		{"+36 bp", model.Variant{Type: model.InsertionMutation, Length: 36}},
+		{"Δ1,234 bp", model.Variant{Type: model.DeletionMutation, Length: 1234}},
+		{"2 bp→AT", model.Variant{Type: model.SubstitutionMutation, AltBases: "AT", Length: 2}},
+		{"AC→TT", model.Variant{Type: model.SubstitutionMutation, RefBases: "AC", AltBases: "TT", Length: 2}},
+		{"100 bp→REL606:8000-8099", model.Variant{Type: model.SubstitutionMutation, Length: 100}},
+		{"(TA)6→5", model.Variant{Type: model.RepeatMutation, RepeatUnit: "TA", RefCopies: 6, AltCopies: 5}},
+		{"IS150 (+) +4 bp", model.Variant{Type: model.MobileElementMutation, MobileElement: "IS150", Strand: "+", Duplication: 4}},
+		{"IS150 (–) +3 bp :: Δ1 bp", model.Variant{Type: model.MobileElementMutation, MobileElement: "IS150", Strand: "-", Duplication: 3}},
+		{"IS1 (-)", model.Variant{Type: model.MobileElementMutation, MobileElement: "IS1", Strand: "-"}},
+		{"1,200 bp x 2", model.Variant{Type: model.AmplificationMutation, Length: 1200, RefCopies: 1, AltCopies: 2}},
+		{"500 bp inversion", model.Variant{Type: model.InversionMutation, Length: 500}},
+	}
+
+	for _, c := range cases {
+		variant, ok := parseMutation(c.mutation)
+		assert.True(t, ok, c.mutation)
+		assert.Equal(t, c.expected, variant, c.mutation)
+	}
+}
+
+func TestParseMutationUnknown(t *testing.T) {
+	for _, mutation := range []string{"", "unknown", "A→", "Δ bp", "+1.5 bp"} {
+		variant, ok := parseMutation(mutation)
+		assert.False(t, ok, mutation)
+		assert.Equal(t, model.Variant{}, variant, mutation)
+	}
+}
@@ -0,0 +1,101 @@
+package parse
+
+import (
+	"context"
+	"github.com/bio-pdv/tools/model"
+	"io"
+	"time"
+)
+
+// FileResult is the outcome of parsing a single file with ParseFiles.
+type FileResult struct {
+	FilePath string
+	Results  [][]model.SequenceAnnotation
+	// Warnings are the repairs made parsing the file leniently.
+	Warnings []*ParseError
+	Err      error
+	// Duration is how long the file took to parse.
+	Duration time.Duration
+}
+
+// ParseFiles parses the files concurrently with up to jobs at a time, the same way as
+// ParseSeqAnnotationDataFilePath. Each file's warnings are collected into its result,
+// in place of the opts' OnWarning. Each file's result is sent on the returned channel in
+// the order of the filepaths, regardless of which finishes first, so at most jobs files
+// are ever held in memory waiting to be received. The channel is closed once every file
+// is sent.
+//
+// Cancelling the context stops new files from being parsed and closes the channel early,
+// without the results of the files still in flight. Those files stop parsing at their
+// next read, rather than running to completion. Check the context's error to tell an
+// early close apart from a complete one.
+func ParseFiles(ctx context.Context, filePaths []string, jobs int, fileType string, appName string, version string, opts Options) <-chan FileResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	// Each file gets its own single result slot, queued in order. The queue's capacity,
+	// plus the slot being waited on, bounds the files in flight to the number of jobs.
+	pending := make(chan chan FileResult, jobs-1)
+	go func() {
+		defer close(pending)
+		for _, filePath := range filePaths {
+			slot := make(chan FileResult, 1)
+			select {
+			case pending <- slot:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(filePath string) {
+				start := time.Now()
+				warnings := []*ParseError{}
+				fileOpts := opts
+				fileOpts.OnWarning = func(warning *ParseError) {
+					warnings = append(warnings, warning)
+				}
+				results, err := parseSeqAnnotationDataFilePath(ctx, filePath, fileType, appName, version, fileOpts)
+				slot <- FileResult{filePath, results, warnings, err, time.Since(start)}
+			}(filePath)
+		}
+	}()
+
+	out := make(chan FileResult)
+	go func() {
+		defer close(out)
+		for slot := range pending {
+			var result FileResult
+			select {
+			case result = <-slot:
+			case <-ctx.Done():
+				return
+			}
+
+			// A select picks at random between ready cases, so check first that
+			// the context isn't already cancelled.
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// contextReader fails reading with the context's error once it's cancelled.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
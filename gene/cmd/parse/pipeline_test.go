@@ -0,0 +1,126 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestGdFiles(t *testing.T, count int) []string {
+	dir := t.TempDir()
+	filePaths := make([]string, count)
+	for i := range filePaths {
+		filePaths[i] = filepath.Join(dir, fmt.Sprintf("%d.gd", i))
+		assert.Nil(t, ioutil.WriteFile(filePaths[i], []byte(validBreseqGd), 0600))
+	}
+	return filePaths
+}
+
+func TestParseFiles(t *testing.T) {
+	filePaths := writeTestGdFiles(t, 8)
+	filePaths[3] = filepath.Join(filepath.Dir(filePaths[3]), "missing.gd")
+
+	for _, jobs := range []int{0, 1, 3, 16} {
+		i := 0
+		for result := range ParseFiles(context.Background(), filePaths, jobs, "gd", "breseq", "0.27", Options{}) {
+			assert.Equal(t, filePaths[i], result.FilePath, "jobs: %d", jobs)
+			if i == 3 {
+				assert.NotNil(t, result.Err)
+			} else {
+				assert.Nil(t, result.Err)
+				assert.Equal(t, 8, len(result.Results[0]))
+				assert.Equal(t, filePaths[i], result.Results[0][0].Source)
+			}
+			i++
+		}
+		assert.Equal(t, len(filePaths), i, "jobs: %d", jobs)
+	}
+}
+
+func TestParseFilesCancelled(t *testing.T) {
+	filePaths := writeTestGdFiles(t, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	results := ParseFiles(ctx, filePaths, 2, "gd", "breseq", "0.27", Options{})
+	first := <-results
+	assert.Equal(t, filePaths[0], first.FilePath)
+	cancel()
+
+	received := 1
+	for range results {
+		received++
+	}
+	assert.Less(t, received, len(filePaths))
+	assert.NotNil(t, ctx.Err())
+}
+
+// blockingParser reads the first byte of each file, then waits to be released before
+// reading the rest, and sends the error from reading the rest on done.
+type blockingParser struct {
+	started chan struct{}
+	release chan struct{}
+	done    chan error
+}
+
+func (p *blockingParser) Detect([]byte, Detection) bool {
+	return false
+}
+
+func (p *blockingParser) Parse(reader io.Reader) ([][]model.SequenceAnnotation, error) {
+	_, err := reader.Read(make([]byte, 1))
+	p.started <- struct{}{}
+	<-p.release
+	if err == nil {
+		_, err = ioutil.ReadAll(reader)
+	}
+	p.done <- err
+	return [][]model.SequenceAnnotation{}, err
+}
+
+func TestParseFilesCancelsInFlight(t *testing.T) {
+	key := ParserKey{"txt", "blocking", "1.0"}
+	parser := &blockingParser{make(chan struct{}), make(chan struct{}), make(chan error, 2)}
+	unregister := registerTestParser(t, key, parser)
+	defer unregister()
+
+	filePaths := writeTestGdFiles(t, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	results := ParseFiles(ctx, filePaths, 2, key.FileType, key.Application, key.Version, Options{})
+	for range filePaths {
+		<-parser.started
+	}
+	cancel()
+	close(parser.release)
+
+	// Both files in flight stop reading, rather than being parsed to the end.
+	for range filePaths {
+		assert.Equal(t, context.Canceled, <-parser.done)
+	}
+	for range results {
+		assert.Fail(t, "Received a result after the cancel")
+	}
+}
+
+func TestParseFilesLenient(t *testing.T) {
+	dir := t.TempDir()
+	filePaths := []string{filepath.Join(dir, "a.html"), filepath.Join(dir, "b.html")}
+	assert.Nil(t, ioutil.WriteFile(filePaths[0], []byte(validBreseq027Html), 0600))
+	malformed := strings.Replace(validBreseq027Html, "</tr>\n<!-- End Table Row -->\n</table>", "</table>", 1)
+	assert.Nil(t, ioutil.WriteFile(filePaths[1], []byte(malformed), 0600))
+
+	warnings := []int{}
+	for result := range ParseFiles(context.Background(), filePaths, 2, "", "", "", Options{Lenient: true}) {
+		assert.Nil(t, result.Err)
+		assert.Equal(t, 2, len(result.Results[0]))
+		warnings = append(warnings, len(result.Warnings))
+		for _, warning := range result.Warnings {
+			assert.Equal(t, result.FilePath, warning.FilePath)
+		}
+	}
+	assert.Equal(t, []int{0, 1}, warnings)
+}
@@ -0,0 +1,318 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	parserKeySeparator = "/"
+
+	errIncompleteParserKeyMsgFmt = "Parser key is missing a file type, application, or version: '%s'"
+	errDuplicateParserMsgFmt     = "A parser is already registered for: '%s'"
+	errNilParserMsgFmt           = "Cannot register a nil parser for: '%s'"
+	errUnsupportedParserMsgFmt   = "No parser is registered for: '%s'. Available parsers: %s"
+	errUndetectedParserMsgFmt    = "No registered parser recognized the file. Detected file type: '%s' application: '%s' version: '%s'. Available parsers: %s"
+	errAmbiguousParserMsgFmt     = "Multiple parsers recognized the file: %s. Please provide the file type, application, and/or version explicitly."
+	selectedParserMsgFmt         = "Selected parser: '%s'\n"
+)
+
+// ParserKey identifies the file type, application, and version
+// of the sequence annotation files a parser handles.
+type ParserKey struct {
+	FileType    string
+	Application string
+	Version     string
+}
+
+// String formats the key as 'fileType/application/version', e.g. 'html/breseq/0.27'.
+func (k ParserKey) String() string {
+	return strings.Join([]string{k.FileType, k.Application, k.Version}, parserKeySeparator)
+}
+
+// normalize lower cases the file type and application, and reduces the version
+// down to its major.minor number, so lookups are insensitive to either.
+func (k ParserKey) normalize() ParserKey {
+	return ParserKey{
+		FileType:    strings.ToLower(strings.TrimSpace(k.FileType)),
+		Application: strings.ToLower(strings.TrimSpace(k.Application)),
+		Version:     normalizeAppVersion(k.Version),
+	}
+}
+
+// matches checks the key against a partial key, where empty fields match anything.
+func (k ParserKey) matches(partial ParserKey) bool {
+	return (partial.FileType == "" || partial.FileType == k.FileType) &&
+		(partial.Application == "" || partial.Application == k.Application) &&
+		(partial.Version == "" || partial.Version == k.Version)
+}
+
+// Parser converts a single file type, generated by a specific version of
+// an application, into sequence annotations.
+type Parser interface {
+	// Detect reports whether the beginning of a file looks like the file type,
+	// application, and version this parser handles. The detection is what the
+	// built-in sniffer found in the prefix, so parsers of the formats it knows
+	// don't need to sniff it again. Others can inspect the prefix themselves.
+	Detect(prefix []byte, detection Detection) bool
+	// Parse reads the whole file. Returns a slice of slices where each slice
+	// represents a single collection of sequence annotations.
+	Parse(reader io.Reader) ([][]model.SequenceAnnotation, error)
+}
+
+// SeqAnnotationHandler receives each sequence annotation streamed from a file, along
+// with the index of the collection it's in. Returning an error stops the parsing.
+type SeqAnnotationHandler func(collection int, sa model.SequenceAnnotation) error
+
+// StreamParser is a Parser that can also hand off each sequence annotation as soon as
+// it's read, so memory stays constant however large the file is.
+type StreamParser interface {
+	Parser
+	// ParseStream reads the whole file, handing each sequence annotation to the
+	// handler in order. Returns the first error from parsing or the handler.
+	ParseStream(reader io.Reader, handler SeqAnnotationHandler) error
+}
+
+// WarningHandler receives each problem found in a file that was repaired, rather than
+// stopping the parsing, located the same way as the errors are.
+type WarningHandler func(warning *ParseError)
+
+// LenientParser is a StreamParser that can also repair malformed files, the way browsers
+// tolerate malformed HTML, rather than stopping at the first problem.
+type LenientParser interface {
+	StreamParser
+	// ParseLenient is the same as ParseStream, except problems that can be repaired are
+	// handed to onWarning, if it's given, and the parsing carries on.
+	ParseLenient(reader io.Reader, handler SeqAnnotationHandler, onWarning WarningHandler) error
+}
+
+// collectSeqAnnotations returns a handler collecting the streamed sequence annotations
+// into the results, the same as a Parser's Parse returns them.
+func collectSeqAnnotations(results *[][]model.SequenceAnnotation) SeqAnnotationHandler {
+	return func(collection int, sa model.SequenceAnnotation) error {
+		for len(*results) <= collection {
+			*results = append(*results, []model.SequenceAnnotation{})
+		}
+		(*results)[collection] = append((*results)[collection], sa)
+		return nil
+	}
+}
+
+var (
+	parserRegistryMu sync.RWMutex
+	parserRegistry   = map[ParserKey]Parser{}
+)
+
+func init() {
+	for vers := range breseqHtmlLayouts {
+		MustRegisterParser(ParserKey{string(htmlFileType), string(breseq), string(vers)}, &breseqHtmlParser{version: vers})
+		MustRegisterParser(ParserKey{string(gdFileType), string(breseq), string(vers)}, &breseqGdParser{version: vers})
+	}
+}
+
+// RegisterParser adds a parser to the registry, so it can be used by ParseSeqAnnotationData
+// and picked up by auto-detection. Returns an error if the key is incomplete, or another
+// parser is already registered for it.
+func RegisterParser(key ParserKey, parser Parser) error {
+	key = key.normalize()
+	if key.FileType == "" || key.Application == "" || key.Version == "" {
+		return fmt.Errorf(errIncompleteParserKeyMsgFmt, key)
+	}
+
+	if parser == nil {
+		return fmt.Errorf(errNilParserMsgFmt, key)
+	}
+
+	parserRegistryMu.Lock()
+	defer parserRegistryMu.Unlock()
+	if _, exists := parserRegistry[key]; exists {
+		return fmt.Errorf(errDuplicateParserMsgFmt, key)
+	}
+
+	parserRegistry[key] = parser
+	return nil
+}
+
+// MustRegisterParser is the same as RegisterParser except it panics on the error.
+func MustRegisterParser(key ParserKey, parser Parser) {
+	if err := RegisterParser(key, parser); err != nil {
+		panic(err)
+	}
+}
+
+// Parsers lists the keys of every registered parser in sorted order.
+func Parsers() []ParserKey {
+	parserRegistryMu.RLock()
+	defer parserRegistryMu.RUnlock()
+	keys := make([]ParserKey, 0, len(parserRegistry))
+	for key := range parserRegistry {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// findParser looks up the parser for the file type, application, and version. Any of those
+// left empty are filled in by sniffing the beginning of the reader once, and asking each
+// matching parser to detect it. Returns the parser with a reader to continue parsing with.
+func findParser(reader io.Reader, fileType string, appName string, version string) (Parser, io.Reader, error) {
+	partial := ParserKey{fileType, appName, version}.normalize()
+	if partial.FileType != "" && partial.Application != "" && partial.Version != "" {
+		parserRegistryMu.RLock()
+		parser, ok := parserRegistry[partial]
+		parserRegistryMu.RUnlock()
+		if !ok {
+			return nil, nil, newSentinelError(ErrUnsupportedFormat, fmt.Errorf(errUnsupportedParserMsgFmt, partial, formatParserKeys(Parsers())))
+		}
+		return parser, reader, nil
+	}
+
+	detection, prefix, bufReader, err := detectSeqAnnotationData(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := map[ParserKey]Parser{}
+	parserRegistryMu.RLock()
+	for key, parser := range parserRegistry {
+		if key.matches(partial) {
+			candidates[key] = parser
+		}
+	}
+	parserRegistryMu.RUnlock()
+
+	// A given version fills in one that couldn't be sniffed, e.g. from a
+	// GenomeDiff file without the '#=PROGRAM' metadata.
+	known := detection
+	if known.Version == "" {
+		known.Version = partial.Version
+	}
+
+	detected := []ParserKey{}
+	for key, parser := range candidates {
+		if parser.Detect(prefix, known) {
+			detected = append(detected, key)
+		}
+	}
+
+	switch len(detected) {
+	case 0:
+		if parser, key, ok := givenVersionParser(partial, detection); ok {
+			log.Printf(selectedParserMsgFmt, key)
+			return parser, bufReader, nil
+		}
+		return nil, nil, newSentinelError(ErrUnsupportedFormat, undetectedParserError(detection))
+	case 1:
+		log.Printf(selectedParserMsgFmt, detected[0])
+		return candidates[detected[0]], bufReader, nil
+	}
+
+	sort.Slice(detected, func(i, j int) bool {
+		return detected[i].String() < detected[j].String()
+	})
+	return nil, nil, fmt.Errorf(errAmbiguousParserMsgFmt, formatParserKeys(detected))
+}
+
+// givenVersionParser looks up the parser of the given version for a file detected as another
+// version, so it fails with ErrVersionMismatch the same as when the file type and
+// application are given too, rather than as a file no parser recognized.
+func givenVersionParser(partial ParserKey, detection Detection) (Parser, ParserKey, bool) {
+	if partial.Version == "" || detection.FileType == "" || detection.Application == "" {
+		return nil, ParserKey{}, false
+	}
+
+	key := ParserKey{detection.FileType, detection.Application, partial.Version}.normalize()
+	if !key.matches(partial) {
+		return nil, ParserKey{}, false
+	}
+
+	parserRegistryMu.RLock()
+	parser, ok := parserRegistry[key]
+	parserRegistryMu.RUnlock()
+	return parser, key, ok
+}
+
+// undetectedParserError reports whatever could be sniffed from a file no parser
+// recognized, to help explain why nothing matched.
+func undetectedParserError(detection Detection) error {
+	switch detection.FileType {
+	case "":
+		return errors.New(errUndetectableFileMsg)
+	case string(vcfFileType):
+		return fmt.Errorf(errUnsupportedVcfFileMsgFmt, detection.Application, detection.Version)
+	}
+	return fmt.Errorf(errUndetectedParserMsgFmt, detection.FileType, detection.Application, detection.Version, formatParserKeys(Parsers()))
+}
+
+func formatParserKeys(keys []ParserKey) string {
+	keyStrs := make([]string, len(keys))
+	for i, key := range keys {
+		keyStrs[i] = key.String()
+	}
+	return strings.Join(keyStrs, ", ")
+}
+
+// normalizeAppVersion reduces a version down to its major.minor number, e.g. '0.35'
+// from '0.35.1' or '0.35.*'. Versions in any other form are returned as is.
+func normalizeAppVersion(version string) string {
+	version = strings.TrimSpace(version)
+	matches := appVersionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return version
+	}
+	return matches[1]
+}
+
+// breseqHtmlParser parses the index.html file of a single breseq version.
+type breseqHtmlParser struct {
+	version appVersion
+}
+
+func (p *breseqHtmlParser) Detect(_ []byte, detection Detection) bool {
+	return detection.FileType == string(htmlFileType) &&
+		detection.Application == string(breseq) &&
+		normalizeAppVersion(detection.Version) == string(p.version)
+}
+
+func (p *breseqHtmlParser) Parse(reader io.Reader) ([][]model.SequenceAnnotation, error) {
+	return parseBreseqHtmlFile(reader, p.version)
+}
+
+func (p *breseqHtmlParser) ParseStream(reader io.Reader, handler SeqAnnotationHandler) error {
+	return streamBreseqHtmlFile(reader, p.version, handler, nil)
+}
+
+func (p *breseqHtmlParser) ParseLenient(reader io.Reader, handler SeqAnnotationHandler, onWarning WarningHandler) error {
+	if onWarning == nil {
+		onWarning = func(*ParseError) {}
+	}
+	return streamBreseqHtmlFile(reader, p.version, handler, onWarning)
+}
+
+// breseqGdParser parses the GenomeDiff files of a single breseq version. Since the
+// '#=PROGRAM' metadata is optional, a GenomeDiff file without one, and without a
+// version given, is detected by the newest version's parser.
+type breseqGdParser struct {
+	version appVersion
+}
+
+func (p *breseqGdParser) Detect(_ []byte, detection Detection) bool {
+	version := normalizeAppVersion(detection.Version)
+	if version == "" {
+		version = string(breseqLatestVersNumber)
+	}
+	return detection.FileType == string(gdFileType) && version == string(p.version)
+}
+
+func (p *breseqGdParser) Parse(reader io.Reader) ([][]model.SequenceAnnotation, error) {
+	return parseBreseqGdFile(reader, string(p.version))
+}
@@ -0,0 +1,266 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const (
+	testCsvSignature = "seq_id,position"
+	testCsvData      = testCsvSignature + "\nREL606,12345\n"
+)
+
+// testCsvParser is an in-house format parser for testing the registry. It keeps
+// the detections it's handed, to check the prefix is only sniffed once.
+type testCsvParser struct {
+	detections []Detection
+}
+
+func (p *testCsvParser) Detect(prefix []byte, detection Detection) bool {
+	p.detections = append(p.detections, detection)
+	return bytes.HasPrefix(prefix, []byte(testCsvSignature))
+}
+
+func (p *testCsvParser) Parse(reader io.Reader) ([][]model.SequenceAnnotation, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	results := []model.SequenceAnnotation{}
+	for _, line := range lines[1:] {
+		cols := strings.Split(line, ",")
+		results = append(results, model.SequenceAnnotation{SequenceId: cols[0], Position: cols[1]})
+	}
+	return [][]model.SequenceAnnotation{results}, nil
+}
+
+// registerTestParser registers the parser and returns a func to remove it again.
+func registerTestParser(t *testing.T, key ParserKey, parser Parser) func() {
+	assert.Nil(t, RegisterParser(key, parser))
+	return func() {
+		parserRegistryMu.Lock()
+		delete(parserRegistry, key.normalize())
+		parserRegistryMu.Unlock()
+	}
+}
+
+func TestRegisterParser(t *testing.T) {
+	key, parser := ParserKey{"CSV", "In-House", "1.2.3"}, &testCsvParser{}
+	unregister := registerTestParser(t, key, parser)
+	defer unregister()
+
+	assert.Contains(t, Parsers(), ParserKey{"csv", "in-house", "1.2"})
+
+	testResults, testErr := ParseSeqAnnotationData(strings.NewReader(testCsvData), "csv", "in-house", "1.2", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, 1, len(testResults))
+	assert.Equal(t, "REL606", testResults[0][0].SequenceId)
+
+	testResults, testErr = ParseSeqAnnotationData(strings.NewReader(testCsvData), "", "", "", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, "12345", testResults[0][0].Position)
+	assert.Equal(t, []Detection{{}}, parser.detections)
+
+	parser.detections = nil
+	_, testErr = ParseSeqAnnotationData(strings.NewReader(validBreseq027Html), "", "", "", Options{})
+	assert.Nil(t, testErr)
+	assert.Equal(t, []Detection{{"html", "breseq", "0.27"}}, parser.detections)
+}
+
+func TestRegisterParserInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		key    ParserKey
+		parser Parser
+	}{
+		{"Missing File Type", ParserKey{"", "breseq", "0.27"}, &testCsvParser{}},
+		{"Missing Application", ParserKey{"csv", " ", "0.27"}, &testCsvParser{}},
+		{"Missing Version", ParserKey{"csv", "breseq", ""}, &testCsvParser{}},
+		{"Nil Parser", ParserKey{"csv", "breseq", "0.27"}, nil},
+		{"Duplicate", ParserKey{"HTML", "breseq", "0.27.*"}, &testCsvParser{}},
+	}
+
+	for _, c := range cases {
+		assert.NotNil(t, RegisterParser(c.key, c.parser), c.name)
+	}
+}
+
+func TestParsers(t *testing.T) {
+	keys := Parsers()
+	assert.Equal(t, 2*len(breseqHtmlLayouts), len(keys))
+	assert.Equal(t, ParserKey{"gd", "breseq", "0.27"}, keys[0])
+	assert.Equal(t, ParserKey{"html", "breseq", "0.38"}, keys[len(keys)-1])
+	for vers := range breseqHtmlLayouts {
+		assert.Contains(t, keys, ParserKey{"html", "breseq", string(vers)})
+		assert.Contains(t, keys, ParserKey{"gd", "breseq", string(vers)})
+	}
+}
+
+func TestFindParser(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		key      ParserKey
+		expected Parser
+	}{
+		{
+			name:     "Explicit",
+			data:     "",
+			key:      ParserKey{"html", "breseq", "0.30.1"},
+			expected: &breseqHtmlParser{version: "0.30"},
+		},
+		{
+			name:     "Detected HTML",
+			data:     readBreseqHtmlFixture(t, "0.33.2", polymorphismLayoutName),
+			key:      ParserKey{},
+			expected: &breseqHtmlParser{version: "0.33"},
+		},
+		{
+			name:     "Detected GenomeDiff Version",
+			data:     testGdProgramHeader,
+			key:      ParserKey{},
+			expected: &breseqGdParser{version: "0.35"},
+		},
+		{
+			name:     "Given GenomeDiff Version",
+			data:     validBreseqGd,
+			key:      ParserKey{Version: "0.29"},
+			expected: &breseqGdParser{version: "0.29"},
+		},
+		{
+			name:     "Undetected GenomeDiff Version",
+			data:     validBreseqGd,
+			key:      ParserKey{},
+			expected: &breseqGdParser{version: breseqLatestVersNumber},
+		},
+		{
+			name:     "Undetected GenomeDiff Version Given File Type",
+			data:     validBreseqGd,
+			key:      ParserKey{FileType: "gd"},
+			expected: &breseqGdParser{version: breseqLatestVersNumber},
+		},
+	}
+
+	for _, c := range cases {
+		parser, reader, err := findParser(strings.NewReader(c.data), c.key.FileType, c.key.Application, c.key.Version)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.expected, parser, c.name)
+		replayed, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.data, string(replayed), c.name)
+	}
+}
+
+func TestFindParserInvalid(t *testing.T) {
+	unregister := registerTestParser(t, ParserKey{"csv", "in-house", "1.2"}, &testCsvParser{})
+	defer unregister()
+	unregister = registerTestParser(t, ParserKey{"csv", "in-house", "1.3"}, &testCsvParser{})
+	defer unregister()
+
+	cases := []struct {
+		name string
+		data string
+		key  ParserKey
+	}{
+		{"Unregistered", validBreseq027Html, ParserKey{"vcf", "breseq", "0.27"}},
+		{"Unregistered Version", validBreseq027Html, ParserKey{"html", "breseq", "0.39"}},
+		{"Ambiguous", testCsvData, ParserKey{}},
+		{"Mismatched File Type", validBreseqGd, ParserKey{FileType: "html"}},
+		{"Mismatched Version", validBreseq027Html, ParserKey{FileType: "gd", Version: "0.28"}},
+		{"Undetectable", "random text", ParserKey{}},
+	}
+
+	for _, c := range cases {
+		parser, reader, err := findParser(strings.NewReader(c.data), c.key.FileType, c.key.Application, c.key.Version)
+		assert.NotNil(t, err, c.name)
+		assert.Nil(t, parser, c.name)
+		assert.Nil(t, reader, c.name)
+	}
+}
+
+func TestParseSeqAnnotationDataVersionMismatch(t *testing.T) {
+	htmlData := readBreseqHtmlFixture(t, "0.35.1", polymorphismLayoutName)
+	gdData := testGdProgramHeader + "SNP\t1\t.\tREL606\t1000\tA\n"
+	cases := []struct {
+		name     string
+		data     string
+		key      ParserKey
+		expected string
+	}{
+		{"HTML Version", htmlData, ParserKey{Version: "0.27"}, "Expected a breseq 0.27.* HTML file, but got version: '0.35'"},
+		{"HTML Application Version", htmlData, ParserKey{Application: "breseq", Version: "0.27.*"}, "Expected a breseq 0.27.* HTML file, but got version: '0.35'"},
+		{"HTML File Type Version", htmlData, ParserKey{FileType: "html", Version: "0.27"}, "Expected a breseq 0.27.* HTML file, but got version: '0.35'"},
+		{"HTML Explicit", htmlData, ParserKey{"html", "breseq", "0.27.1"}, "Expected a breseq 0.27.* HTML file, but got version: '0.35'"},
+		{"GenomeDiff Version", gdData, ParserKey{Version: "0.30"}, "Expected a breseq 0.30.* GenomeDiff file, but got version: '0.35'"},
+		{"GenomeDiff Explicit", gdData, ParserKey{"gd", "breseq", "0.30"}, "Expected a breseq 0.30.* GenomeDiff file, but got version: '0.35'"},
+	}
+
+	for _, c := range cases {
+		testResults, testErr := ParseSeqAnnotationData(strings.NewReader(c.data), c.key.FileType, c.key.Application, c.key.Version, Options{})
+		assert.Nil(t, testResults, c.name)
+		assert.True(t, errors.Is(testErr, ErrVersionMismatch), c.name)
+		assert.False(t, errors.Is(testErr, ErrUnsupportedFormat), c.name)
+		if testErr != nil {
+			assert.Contains(t, testErr.Error(), c.expected, c.name)
+		}
+	}
+
+	// A version no parser is registered for is unsupported, however it's given.
+	for _, key := range []ParserKey{{Version: "0.99"}, {"html", "breseq", "0.99"}} {
+		_, testErr := ParseSeqAnnotationData(strings.NewReader(htmlData), key.FileType, key.Application, key.Version, Options{})
+		assert.True(t, errors.Is(testErr, ErrUnsupportedFormat), key.String())
+	}
+}
+
+func TestParseSeqAnnotationDataGdWithoutVersion(t *testing.T) {
+	cases := []struct {
+		name     string
+		key      ParserKey
+		expected string
+	}{
+		{"Detected", ParserKey{}, string(breseqLatestVersNumber)},
+		{"Given File Type", ParserKey{FileType: "gd"}, string(breseqLatestVersNumber)},
+		{"Given Version", ParserKey{Version: "0.30"}, "0.30"},
+	}
+
+	for _, c := range cases {
+		testResults, testErr := ParseSeqAnnotationData(strings.NewReader(validBreseqGd), c.key.FileType, c.key.Application, c.key.Version, Options{})
+		assert.Nil(t, testErr, c.name)
+		assert.Equal(t, 1, len(testResults), c.name)
+		assert.Equal(t, 8, len(testResults[0]), c.name)
+		assert.Equal(t, c.expected, testResults[0][0].AppVersion, c.name)
+	}
+}
+
+func TestBreseqLatestVersNumber(t *testing.T) {
+	for vers := range breseqHtmlLayouts {
+		assert.True(t, normalizeAppVersion(string(vers)) <= string(breseqLatestVersNumber), vers)
+	}
+}
+
+func TestNormalizeAppVersion(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected string
+	}{
+		{"0.27", "0.27"},
+		{"0.27.*", "0.27"},
+		{"0.31.1", "0.31"},
+		{" 0.38 ", "0.38"},
+		{"1", "1"},
+		{"latest", "latest"},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, normalizeAppVersion(c.version), c.version)
+	}
+}
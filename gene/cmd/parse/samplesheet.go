@@ -0,0 +1,219 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sampleSheetCsvDelimiter = ','
+	sampleSheetTsvDelimiter = '\t'
+
+	sampleSheetPathColumn       = "path"
+	sampleSheetPopulationColumn = "population"
+	sampleSheetReplicateColumn  = "replicate"
+	sampleSheetGenerationColumn = "generation"
+	sampleSheetTreatmentColumn  = "treatment"
+	sampleSheetTimePointColumn  = "timepoint"
+
+	errOpenSampleSheetMsgFmt        = "Could not open the sample sheet. Filepath: '%s' Error: '%s'"
+	errMalformedSampleSheetMsgFmt   = "Malformed sample sheet. Error: '%s'"
+	errUnknownSampleColumnMsgFmt    = "Unknown sample sheet column: '%s'. Expected: path, population, replicate, generation, treatment, or time point"
+	errDuplicateSampleColumnMsgFmt  = "Duplicate sample sheet column: '%s'"
+	errMissingSamplePathColumnMsg   = "The sample sheet has no path column."
+	errMissingSamplePathMsgFmt      = "Missing path in sample sheet row: %d"
+	errDuplicateSamplePathMsgFmt    = "Duplicate path in sample sheet row: %d Path: '%s'"
+	errSampleNotFoundMsgFmt         = "File is not listed in the sample sheet. Filepath: '%s'"
+	errResolveSampleSheetPathMsgFmt = "Could not resolve the path: '%s' Error: '%s'"
+)
+
+var (
+	// sampleSheetColumns maps the normalized header names to their column, accepting a
+	// few common synonyms.
+	sampleSheetColumns = map[string]string{
+		"path":       sampleSheetPathColumn,
+		"file":       sampleSheetPathColumn,
+		"filepath":   sampleSheetPathColumn,
+		"filename":   sampleSheetPathColumn,
+		"population": sampleSheetPopulationColumn,
+		"replicate":  sampleSheetReplicateColumn,
+		"generation": sampleSheetGenerationColumn,
+		"treatment":  sampleSheetTreatmentColumn,
+		"timepoint":  sampleSheetTimePointColumn,
+		"time":       sampleSheetTimePointColumn,
+	}
+
+	// sampleSheetHeaderReplacer drops the separators, so 'Time Point', 'time_point',
+	// and 'time-point' are all the same column.
+	sampleSheetHeaderReplacer = strings.NewReplacer(" ", "", "_", "", "-", "")
+)
+
+// Sample is the metadata of a sequenced sample, stamped on each of the sequence
+// annotations parsed from its file.
+type Sample struct {
+	Population string
+	Replicate  string
+	Generation string
+	Treatment  string
+	TimePoint  string
+}
+
+// SampleSheet maps the absolute paths of sequence annotation files to their samples.
+type SampleSheet map[string]Sample
+
+// ReadSampleSheetFilePath reads a sample sheet file. Relative paths in the sheet are
+// relative to the sheet's directory.
+func ReadSampleSheetFilePath(filePath string) (SampleSheet, error) {
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(errOpenSampleSheetMsgFmt, filePath, err.Error())
+	}
+	defer reader.Close()
+
+	return ReadSampleSheet(reader, filepath.Dir(filePath))
+}
+
+// ReadSampleSheet reads a CSV or TSV sample sheet, told apart by whether the header
+// has a tab. The header names the columns, in any order: the path of the sequence
+// annotation file, which is required, and the population, replicate, generation,
+// treatment, and time point of its sample. Relative paths are resolved against baseDir.
+func ReadSampleSheet(reader io.Reader, baseDir string) (SampleSheet, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf(errMalformedSampleSheetMsgFmt, err.Error())
+	}
+
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.Comma = sampleSheetCsvDelimiter
+	if header, _, _ := bytes.Cut(data, []byte("\n")); bytes.ContainsRune(header, sampleSheetTsvDelimiter) {
+		csvReader.Comma = sampleSheetTsvDelimiter
+	}
+	csvReader.TrimLeadingSpace = true
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf(errMalformedSampleSheetMsgFmt, err.Error())
+	}
+
+	if len(rows) <= 0 {
+		return nil, fmt.Errorf(errMalformedSampleSheetMsgFmt, errMissingSamplePathColumnMsg)
+	}
+
+	columns, err := parseSampleSheetHeader(rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := SampleSheet{}
+	for i, row := range rows[1:] {
+		fields := map[string]string{}
+		for j, column := range columns {
+			fields[column] = strings.TrimSpace(row[j])
+		}
+
+		// Rows are numbered from 1, counting the header.
+		rowNum := i + 2
+		if fields[sampleSheetPathColumn] == "" {
+			return nil, fmt.Errorf(errMissingSamplePathMsgFmt, rowNum)
+		}
+
+		path, err := resolveSamplePath(fields[sampleSheetPathColumn], baseDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := sheet[path]; ok {
+			return nil, fmt.Errorf(errDuplicateSamplePathMsgFmt, rowNum, fields[sampleSheetPathColumn])
+		}
+
+		sheet[path] = Sample{
+			Population: fields[sampleSheetPopulationColumn],
+			Replicate:  fields[sampleSheetReplicateColumn],
+			Generation: fields[sampleSheetGenerationColumn],
+			Treatment:  fields[sampleSheetTreatmentColumn],
+			TimePoint:  fields[sampleSheetTimePointColumn],
+		}
+	}
+	return sheet, nil
+}
+
+// parseSampleSheetHeader maps each column index to its column.
+func parseSampleSheetHeader(header []string) ([]string, error) {
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		column, ok := sampleSheetColumns[sampleSheetHeaderReplacer.Replace(strings.ToLower(strings.TrimSpace(name)))]
+		if !ok {
+			return nil, fmt.Errorf(errUnknownSampleColumnMsgFmt, name)
+		}
+
+		if seen[column] {
+			return nil, fmt.Errorf(errDuplicateSampleColumnMsgFmt, name)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+
+	if !seen[sampleSheetPathColumn] {
+		return nil, fmt.Errorf(errMalformedSampleSheetMsgFmt, errMissingSamplePathColumnMsg)
+	}
+	return columns, nil
+}
+
+func resolveSamplePath(path string, baseDir string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf(errResolveSampleSheetPathMsgFmt, path, err.Error())
+	}
+	return abs, nil
+}
+
+// Lookup finds the sample of the sequence annotation file. Returns an error if
+// the file isn't listed in the sheet.
+func (s SampleSheet) Lookup(filePath string) (Sample, error) {
+	path, err := resolveSamplePath(filePath, "")
+	if err != nil {
+		return Sample{}, err
+	}
+
+	sample, ok := s[path]
+	if !ok {
+		return Sample{}, fmt.Errorf(errSampleNotFoundMsgFmt, filePath)
+	}
+	return sample, nil
+}
+
+// Stamp sets the sample's metadata on each of the sequence annotations. A UniqueId
+// that was the sequence annotation's ContentId is re-derived with the metadata.
+func (s Sample) Stamp(results [][]model.SequenceAnnotation) {
+	for _, collection := range results {
+		for i := range collection {
+			s.StampSeqAnnotation(&collection[i])
+		}
+	}
+}
+
+// StampSeqAnnotation is the same as Stamp for a single sequence annotation, e.g. one
+// handed off by ParseSeqAnnotationDataStream.
+func (s Sample) StampSeqAnnotation(sa *model.SequenceAnnotation) {
+	hasContentId := sa.UniqueId == sa.ContentId()
+	sa.Population = s.Population
+	sa.Replicate = s.Replicate
+	sa.Generation = s.Generation
+	sa.Treatment = s.Treatment
+	sa.TimePoint = s.TimePoint
+	if hasContentId {
+		sa.UniqueId = sa.ContentId()
+	}
+}
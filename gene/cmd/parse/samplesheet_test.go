@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testSampleSheetCsv = `Path,Population,Replicate,Generation,Treatment,Time Point
+ara-1/500.html,Ara-1,A,500,glucose,day 75
+/data/ara-1/1000.gd, Ara-1 ,B,1000,glucose,day 150
+`
+	testSampleSheetTsv = "file\tpopulation\tgeneration\n" +
+		"ara+1.html\tAra+1\t2000\n"
+)
+
+func TestReadSampleSheet(t *testing.T) {
+	sheet, err := ReadSampleSheet(strings.NewReader(testSampleSheetCsv), "/data")
+	assert.Nil(t, err)
+	assert.Equal(t, SampleSheet{
+		"/data/ara-1/500.html": {Population: "Ara-1", Replicate: "A", Generation: "500", Treatment: "glucose", TimePoint: "day 75"},
+		"/data/ara-1/1000.gd":  {Population: "Ara-1", Replicate: "B", Generation: "1000", Treatment: "glucose", TimePoint: "day 150"},
+	}, sheet)
+
+	sheet, err = ReadSampleSheet(strings.NewReader(testSampleSheetTsv), "/data")
+	assert.Nil(t, err)
+	assert.Equal(t, SampleSheet{"/data/ara+1.html": {Population: "Ara+1", Generation: "2000"}}, sheet)
+}
+
+func TestReadSampleSheetInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		sheet string
+	}{
+		{"Empty", ""},
+		{"No Path Column", "population,generation\nAra-1,500\n"},
+		{"Unknown Column", "path,strain\n500.html,REL606\n"},
+		{"Duplicate Column", "path,generation,generation\n500.html,500,500\n"},
+		{"Missing Path", "path,generation\n,500\n"},
+		{"Duplicate Path", "path,generation\n500.html,500\n./500.html,1000\n"},
+		{"Wrong Field Count", "path,generation\n500.html,500,Ara-1\n"},
+	}
+
+	for _, c := range cases {
+		_, err := ReadSampleSheet(strings.NewReader(c.sheet), "/data")
+		assert.NotNil(t, err, c.name)
+	}
+}
+
+func TestReadSampleSheetFilePath(t *testing.T) {
+	dir := t.TempDir()
+	sheetPath := filepath.Join(dir, "samples.csv")
+	assert.Nil(t, ioutil.WriteFile(sheetPath, []byte("path,generation\n500.html,500\n"), 0600))
+
+	sheet, err := ReadSampleSheetFilePath(sheetPath)
+	assert.Nil(t, err)
+
+	sample, err := sheet.Lookup(filepath.Join(dir, "500.html"))
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{Generation: "500"}, sample)
+
+	_, err = sheet.Lookup(filepath.Join(dir, "1000.html"))
+	assert.NotNil(t, err)
+
+	_, err = ReadSampleSheetFilePath(filepath.Join(dir, "missing.csv"))
+	assert.NotNil(t, err)
+}
+
+func TestSampleStamp(t *testing.T) {
+	sa := model.SequenceAnnotation{SequenceId: "REL606", Position: "12,345", Mutation: "T→G"}
+	sa.UniqueId = sa.ContentId()
+	custom := model.SequenceAnnotation{UniqueId: "custom", SequenceId: "REL606"}
+	results := [][]model.SequenceAnnotation{{sa, custom}}
+
+	Sample{Population: "Ara-1", Replicate: "A", Generation: "500", Treatment: "glucose", TimePoint: "day 75"}.Stamp(results)
+	stamped := results[0][0]
+	assert.Equal(t, "Ara-1", stamped.Population)
+	assert.Equal(t, "A", stamped.Replicate)
+	assert.Equal(t, "500", stamped.Generation)
+	assert.Equal(t, "glucose", stamped.Treatment)
+	assert.Equal(t, "day 75", stamped.TimePoint)
+	assert.Equal(t, stamped.ContentId(), stamped.UniqueId)
+	assert.NotEqual(t, sa.UniqueId, stamped.UniqueId)
+	assert.Equal(t, "custom", results[0][1].UniqueId)
+}
@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/golang-collections/collections/stack"
@@ -22,6 +23,10 @@ const (
 	errUnexpectedTokenTypeMsgFmt = "Parsing encountered an unexpected token of type: %s"
 	errUnknownTokenTypeMsgFmt    = "Parsing unexpected token of type: %s"
 	errUnknownMsg                = "Unknown parsing error encountered"
+
+	// snippetSize is the number of bytes of raw html leading up to a parse error that
+	// are reported along with it.
+	snippetSize = 80
 )
 
 type table []row
@@ -44,12 +49,18 @@ type parserStack interface {
 	Peek() interface{}
 }
 
+// rowHandler receives each row of the tables as soon as its closing tag is parsed,
+// along with the index of the table it's in.
+type rowHandler func(tableIndex int, r row) error
+
 // parserContext is a container for managing the data structures required
 // for parsing nested entities.
 type parserContext struct {
 	sb strings.Builder
 	st parserStack
 	tk parserTokenizer
+	// onRow, if set, is handed each row instead of the row being added to its table.
+	onRow rowHandler
 }
 
 // tagCtr is a container for counting all token entities relevant to table parsing.
@@ -59,21 +70,80 @@ type tagCtr struct {
 	table int
 }
 
+// tokenPosition is where a token starts in the reader. The offset is 0-based,
+// while the line and column are 1-based. Columns count bytes.
+type tokenPosition struct {
+	offset int64
+	line   int
+	column int
+}
+
+// advance moves the position past the raw text of a token.
+func (p tokenPosition) advance(raw []byte) tokenPosition {
+	p.offset += int64(len(raw))
+	if i := bytes.LastIndexByte(raw, '\n'); i >= 0 {
+		p.line += bytes.Count(raw, []byte{'\n'})
+		p.column = len(raw) - i
+	} else {
+		p.column += len(raw)
+	}
+	return p
+}
+
 type persistedTokenizer struct {
 	token     html.Token
 	err       error
 	tokenizer *html.Tokenizer
 	raw       []byte
+	// pos is the position of the current token, and next that of the token after it.
+	pos  tokenPosition
+	next tokenPosition
+	// recent holds the last snippetSize bytes of raw html, up to the end of the current token.
+	recent []byte
 }
 
 func (s *persistedTokenizer) Next() html.TokenType {
 	result := s.tokenizer.Next()
+	// Token changes the contents of Raw in place, e.g. lower casing the tag names,
+	// so the raw html is read first.
+	s.raw = s.tokenizer.Raw()
+	s.pos, s.next = s.next, s.next.advance(s.raw)
+	s.keepRecent(s.raw)
 	s.token = s.tokenizer.Token()
 	s.err = s.tokenizer.Err()
-	s.raw = s.tokenizer.Raw()
 	return result
 }
 
+// keepRecent appends the raw html to the recent bytes, dropping the oldest ones
+// past snippetSize.
+func (s *persistedTokenizer) keepRecent(raw []byte) {
+	if len(raw) >= snippetSize {
+		s.recent = append(s.recent[:0], raw[len(raw)-snippetSize:]...)
+		return
+	}
+
+	if keep := snippetSize - len(raw); len(s.recent) > keep {
+		s.recent = s.recent[:copy(s.recent, s.recent[len(s.recent)-keep:])]
+	}
+	s.recent = append(s.recent, raw...)
+}
+
+// locate sets the position of the current token, along with a snippet of the raw html
+// leading up to it, on the parse error. Errors that aren't a *ParseError are wrapped
+// in one, while those that are already located are left as is.
+func (s *persistedTokenizer) locate(err error) *ParseError {
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		parseErr = &ParseError{Err: err}
+	}
+
+	if parseErr.Column == 0 {
+		parseErr.Line, parseErr.Column, parseErr.Offset = s.pos.line, s.pos.column, s.pos.offset
+		parseErr.Snippet = string(s.recent)
+	}
+	return parseErr
+}
+
 func (s *persistedTokenizer) Token() html.Token {
 	return s.token
 }
@@ -86,10 +156,12 @@ func (s *persistedTokenizer) Raw() []byte {
 	return s.raw
 }
 
-func newPersistedTokenizer(reader io.Reader) parserTokenizer {
+func newPersistedTokenizer(reader io.Reader) *persistedTokenizer {
 	tk := html.NewTokenizer(reader)
 	return &persistedTokenizer{
 		tokenizer: tk,
+		next:      tokenPosition{0, 1, 1},
+		recent:    make([]byte, 0, snippetSize),
 	}
 }
 
@@ -104,16 +176,52 @@ func newPersistedTokenizer(reader io.Reader) parserTokenizer {
 // Any errors encountered in tokenizing the Reader output is returned. Since
 // the tokenizer marks the End-Of-File (EOF) as an error token, this method does
 // that check for the caller and returns back a nil error, if EOF is encountered.
+// Errors about the tables are returned as a *ParseError holding the line, column,
+// and byte offset of the token where parsing stopped, with a snippet of the raw
+// html leading up to it.
 func parseDataTableHtmlTokenizer(reader io.Reader) ([]table, error) {
+	return tokenizeDataTableHtml(reader, nil, nil)
+}
+
+// streamDataTableHtmlTokenizer is the streaming version of parseDataTableHtmlTokenizer.
+// Each row is handed to onRow as soon as its closing tag is parsed rather than kept,
+// so memory stays constant however many rows there are. Returns the number of tables,
+// or the first error from onRow.
+//
+// If onWarning is given, the tables are parsed leniently, see lenientTokenizer, with
+// each repair handed to onWarning.
+func streamDataTableHtmlTokenizer(reader io.Reader, onRow rowHandler, onWarning WarningHandler) (int, error) {
+	tables, err := tokenizeDataTableHtml(reader, onRow, onWarning)
+	return len(tables), err
+}
+
+// tokenizeDataTableHtml parses the tables of the Reader, handing each row to onRow
+// if it's given. The tables returned are left empty in that case. The tables are
+// parsed leniently if onWarning is given.
+func tokenizeDataTableHtml(reader io.Reader, onRow rowHandler, onWarning WarningHandler) ([]table, error) {
 	ctr := &tagCtr{
 		col:   0,
 		row:   0,
 		table: 0,
 	}
+	tk := newPersistedTokenizer(reader)
 	ctx := &parserContext{
 		sb: strings.Builder{},
 		st: stack.New(),
-		tk: newPersistedTokenizer(reader),
+		tk: tk,
+	}
+	if onWarning != nil {
+		ctx.tk = newLenientTokenizer(tk, onWarning)
+	}
+	// Errors from onRow are passed on as is, while the others are about the structure of the tables.
+	// They're flagged rather than compared, since not every error type is comparable.
+	onRowFailed := false
+	if onRow != nil {
+		ctx.onRow = func(tableIndex int, r row) error {
+			err := onRow(tableIndex, r)
+			onRowFailed = err != nil
+			return err
+		}
 	}
 	results := []table{}
 	tableObj, rowObj := new(table), new(row)
@@ -132,7 +240,7 @@ func parseDataTableHtmlTokenizer(reader io.Reader) ([]table, error) {
 			if err != nil {
 				return nil, err
 			}
-			return nil, errors.New(errUnknownMsg)
+			return nil, tk.locate(newSentinelError(ErrMalformedTable, errors.New(errUnknownMsg)))
 		case html.TextToken:
 			log.Printf("Handling Text Tag: %+v\n", token)
 			err = handleTextTagToken(ctx)
@@ -147,6 +255,15 @@ func parseDataTableHtmlTokenizer(reader io.Reader) ([]table, error) {
 			log.Printf("Skipping Tag: %+v\n", token)
 		}
 
+		if err != nil && !onRowFailed {
+			return nil, tk.locate(newSentinelError(ErrMalformedTable, err))
+		}
+
+		if _, ok := err.(*ParseError); ok {
+			// Errors about the row are located at its closing tag.
+			return nil, tk.locate(err)
+		}
+
 		if err != nil {
 			return nil, err
 		}
@@ -288,8 +405,15 @@ func handleEndTagToken(rowObj *row, tableObj *table, results *[]table, ctr *tagC
 			}
 
 			if ctr.row == 0 {
-				log.Println("Adding row to table")
-				*tableObj = append(*tableObj, *rowObj)
+				if ctx.onRow != nil {
+					log.Println("Handing off row")
+					if err := ctx.onRow(len(*results), *rowObj); err != nil {
+						return err
+					}
+				} else {
+					log.Println("Adding row to table")
+					*tableObj = append(*tableObj, *rowObj)
+				}
 				*rowObj = *new(row)
 			}
 		case atom.Th:
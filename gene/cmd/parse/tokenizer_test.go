@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/net/html"
@@ -15,6 +16,16 @@ const (
 	wellFormedHtmlTableString = `<table><tr><td>` + testVal + ` <i>` + itTestVal + `</i></td></tr></table>`
 )
 
+// uncomparableError panics when compared with ==, like mongo.BulkWriteException,
+// since it's used by value and holds a slice.
+type uncomparableError struct {
+	msgs []string
+}
+
+func (e uncomparableError) Error() string {
+	return strings.Join(e.msgs, ", ")
+}
+
 type mockStack struct {
 	mock.Mock
 }
@@ -74,6 +85,105 @@ func TestParseDataTableHtmlTokenizer(t *testing.T) {
 	assert.Equal(t, testVal+" "+itTestVal, testColVal)
 }
 
+func TestStreamDataTableHtmlTokenizer(t *testing.T) {
+	testReader := strings.NewReader(wellFormedHtmlTableString + "<table><tr><td>a</td></tr><tr><td>b</td></tr></table>")
+	tableIndexes, rows := []int{}, []row{}
+	testTables, testErr := streamDataTableHtmlTokenizer(testReader, func(tableIndex int, r row) error {
+		tableIndexes = append(tableIndexes, tableIndex)
+		rows = append(rows, r)
+		return nil
+	}, nil)
+	assert.Nil(t, testErr)
+	assert.Equal(t, 2, testTables)
+	assert.Equal(t, []int{0, 1, 1}, tableIndexes)
+	assert.Equal(t, []row{{testVal + " " + itTestVal}, {"a"}, {"b"}}, rows)
+
+	handlerErr := errors.New("stop")
+	testTables, testErr = streamDataTableHtmlTokenizer(strings.NewReader(wellFormedHtmlTableString), func(int, row) error {
+		return handlerErr
+	}, nil)
+	assert.Equal(t, handlerErr, testErr)
+	assert.Equal(t, 0, testTables)
+}
+
+func TestStreamDataTableHtmlTokenizerUncomparableError(t *testing.T) {
+	handlerErr := uncomparableError{msgs: []string{"write failed", "duplicate"}}
+	for _, onWarning := range []WarningHandler{nil, func(*ParseError) {}} {
+		var testErr error
+		assert.NotPanics(t, func() {
+			_, testErr = streamDataTableHtmlTokenizer(strings.NewReader(wellFormedHtmlTableString), func(int, row) error {
+				return handlerErr
+			}, onWarning)
+		})
+
+		// The handler's error is passed on as is, rather than as a malformed table.
+		var uncomparable uncomparableError
+		assert.True(t, errors.As(testErr, &uncomparable))
+		assert.Equal(t, handlerErr.msgs, uncomparable.msgs)
+		assert.False(t, errors.Is(testErr, ErrMalformedTable))
+	}
+}
+
+func TestPersistedTokenizerPosition(t *testing.T) {
+	tk := newPersistedTokenizer(strings.NewReader("<table>\n  <TR><td>a</td></tr>\n</table>"))
+	expected := []struct {
+		raw      string
+		position tokenPosition
+	}{
+		{"<table>", tokenPosition{0, 1, 1}},
+		{"\n  ", tokenPosition{7, 1, 8}},
+		{"<TR>", tokenPosition{10, 2, 3}},
+		{"<td>", tokenPosition{14, 2, 7}},
+		{"a", tokenPosition{18, 2, 11}},
+		{"</td>", tokenPosition{19, 2, 12}},
+		{"</tr>", tokenPosition{24, 2, 17}},
+		{"\n", tokenPosition{29, 2, 22}},
+		{"</table>", tokenPosition{30, 3, 1}},
+	}
+
+	for _, e := range expected {
+		tk.Next()
+		assert.Equal(t, e.position, tk.pos, e.raw)
+	}
+	assert.Equal(t, "<table>\n  <TR><td>a</td></tr>\n</table>", string(tk.recent))
+	assert.Equal(t, html.ErrorToken, tk.Next())
+}
+
+func TestPersistedTokenizerRecent(t *testing.T) {
+	text := strings.Repeat("x", snippetSize-5)
+	long := strings.Repeat("y", 2*snippetSize)
+	tk := newPersistedTokenizer(strings.NewReader("<td>" + text + "</td>" + long + "<td>"))
+	tk.Next()
+	tk.Next()
+	assert.Equal(t, "<td>"+text, string(tk.recent))
+	tk.Next()
+	assert.Equal(t, text+"</td>", string(tk.recent))
+	tk.Next()
+	assert.Equal(t, long[snippetSize:], string(tk.recent))
+	tk.Next()
+	assert.Equal(t, long[snippetSize+4:]+"<td>", string(tk.recent))
+}
+
+func TestParseDataTableHtmlTokenizerErrorPosition(t *testing.T) {
+	_, testErr := parseDataTableHtmlTokenizer(strings.NewReader("<table>\n<tr><td>a</td>\n</table>"))
+	assert.True(t, errors.Is(testErr, ErrMalformedTable))
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(testErr, &parseErr))
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Equal(t, 1, parseErr.Column)
+	assert.Equal(t, int64(23), parseErr.Offset)
+	assert.Equal(t, "<table>\n<tr><td>a</td>\n</table>", parseErr.Snippet)
+	assert.Contains(t, testErr.Error(), "Line: 3 Column: 1 Offset: 23")
+
+	// Errors from the row handler are located at the row's closing tag.
+	_, testErr = streamDataTableHtmlTokenizer(strings.NewReader("<table>\n<tr><td>a</td></tr></table>"), func(int, row) error {
+		return &ParseError{Row: 1, Err: ErrMalformedTable}
+	}, nil)
+	assert.True(t, errors.As(testErr, &parseErr))
+	assert.Equal(t, ParseError{Row: 1, Line: 2, Column: 15, Offset: 22, Snippet: "<table>\n<tr><td>a</td></tr>", Err: ErrMalformedTable}, *parseErr)
+}
+
 func TestHandleTextTagToken(t *testing.T) {
 	testString := "Test String"
 	mockSt, mTokenizer := new(mockStack), new(mockTokenizer)
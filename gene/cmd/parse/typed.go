@@ -0,0 +1,109 @@
+package parse
+
+import (
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	positionSeparator   = ","
+	insertionSeparator  = ":"
+	percentSuffix       = "%"
+	clonalFrequency     = 1.0
+	noGeneName          = "-"
+	geneListSeparator   = ','
+	geneRangeSeparator  = '–'
+	leftGeneArrow       = '←'
+	rightGeneArrow      = '→'
+	intergenicSeparator = '/'
+
+	errMalformedPositionMsgFmt  = "Malformed position: '%s'. Expected a whole number, e.g. '12,345'"
+	errMalformedFrequencyMsgFmt = "Malformed frequency: '%s'. Expected a percentage within [0%%, 100%%], e.g. '6.0%%'"
+	errMalformedRowMsgFmt       = "Malformed sequence annotation in table: %d row: %d. Error: '%s'"
+)
+
+// populateTypedFields sets the typed companions of the position, frequency, gene,
+// mutation, and annotation fields from their text. Mutations and annotations of an
+// unknown form are left unclassified.
+// Returns an error if the position or frequency is malformed.
+func populateTypedFields(sa *model.SequenceAnnotation) error {
+	position, err := parsePosition(sa.Position)
+	if err != nil {
+		return err
+	}
+
+	frequency, err := parseFrequency(sa.Frequency)
+	if err != nil {
+		return err
+	}
+
+	variant, ok := parseMutation(sa.Mutation)
+	if !ok {
+		log.Printf("Could not classify mutation: '%s'\n", sa.Mutation)
+	}
+
+	effect, ok := parseAnnotation(sa.Annotation)
+	if !ok && sa.Annotation != "" {
+		log.Printf("Could not classify annotation: '%s'\n", sa.Annotation)
+	}
+
+	sa.PositionValue = position
+	sa.FrequencyValue = frequency
+	sa.Genes = parseGeneNames(sa.Gene)
+	sa.GeneContext = parseGeneContext(sa.Gene)
+	sa.Variant = variant
+	sa.Effect = effect
+	return nil
+}
+
+// parsePosition strips the thousands separators out of a position, e.g. '12,345'.
+// Positions of insertions after a base, e.g. '12,345:1', are the base's position.
+func parsePosition(position string) (int64, error) {
+	trimmed := strings.TrimSpace(position)
+	if sepIdx := strings.Index(trimmed, insertionSeparator); sepIdx >= 0 {
+		trimmed = trimmed[:sepIdx]
+	}
+
+	n, err := strconv.ParseInt(strings.Replace(trimmed, positionSeparator, "", -1), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf(errMalformedPositionMsgFmt, position)
+	}
+	return n, nil
+}
+
+// parseFrequency converts a percentage, e.g. '6.0%', into a fraction within [0, 1].
+// An empty frequency is from a clonal sample, where every mutation is fixed.
+func parseFrequency(frequency string) (float64, error) {
+	trimmed := strings.TrimSpace(frequency)
+	if trimmed == "" {
+		return clonalFrequency, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(trimmed, percentSuffix), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf(errMalformedFrequencyMsgFmt, frequency)
+	}
+	return percent / 100, nil
+}
+
+// parseGeneNames splits the gene column into the names of the genes, dropping the
+// strand arrows, intergenic and range separators, brackets, and list commas, e.g.
+// [abcB abcC] from 'abcB ← / → abcC', and [abcD abcE] from '[abcD]–[abcE]'.
+func parseGeneNames(gene string) []string {
+	fields := strings.FieldsFunc(gene, func(r rune) bool {
+		return unicode.IsSpace(r) || r == leftGeneArrow || r == rightGeneArrow ||
+			r == intergenicSeparator || r == geneRangeSeparator || r == geneListSeparator || r == '[' || r == ']'
+	})
+
+	genes := []string{}
+	for _, field := range fields {
+		if field != noGeneName {
+			genes = append(genes, field)
+		}
+	}
+	return genes
+}
@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	cases := []struct {
+		position string
+		expected int64
+	}{
+		{"12,345", 12345},
+		{"1,234,567", 1234567},
+		{"123", 123},
+		{" 65,431 ", 65431},
+		{"12,345:1", 12345},
+	}
+
+	for _, c := range cases {
+		position, err := parsePosition(c.position)
+		assert.Nil(t, err, c.position)
+		assert.Equal(t, c.expected, position, c.position)
+	}
+}
+
+func TestParsePositionInvalid(t *testing.T) {
+	for _, position := range []string{"", "12,345test", "-12", "1.5", "abc"} {
+		_, err := parsePosition(position)
+		assert.NotNil(t, err, position)
+	}
+}
+
+func TestParseFrequency(t *testing.T) {
+	cases := []struct {
+		frequency string
+		expected  float64
+	}{
+		{"6.0%", 0.06},
+		{"100%", 1},
+		{"0%", 0},
+		{"12.5", 0.125},
+		{"", 1},
+	}
+
+	for _, c := range cases {
+		frequency, err := parseFrequency(c.frequency)
+		assert.Nil(t, err, c.frequency)
+		assert.InDelta(t, c.expected, frequency, 1e-9, c.frequency)
+	}
+}
+
+func TestParseFrequencyInvalid(t *testing.T) {
+	for _, frequency := range []string{"12%test", "101%", "-1%", "%"} {
+		_, err := parseFrequency(frequency)
+		assert.NotNil(t, err, frequency)
+	}
+}
+
+func TestParseGeneNames(t *testing.T) {
+	cases := []struct {
+		gene     string
+		expected []string
+	}{
+		{"abcA →", []string{"abcA"}},
+		{"abcB\u00A0←\u00A0/\u00A0→\u00A0abcC", []string{"abcB", "abcC"}},
+		{"[abcD]–[abcE]", []string{"abcD", "abcE"}},
+		{"abcF, abcG, insB-1", []string{"abcF", "abcG", "insB-1"}},
+		{"– / → abcH", []string{"abcH"}},
+		{"", []string{}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, parseGeneNames(c.gene), c.gene)
+	}
+}
+
+func TestParseBreseqHtmlFileMalformedPosition(t *testing.T) {
+	fixture := strings.Replace(validBreseq027Html, `<td align="right">`+testPosition+`</td>`, `<td align="right">12,34x</td>`, 1)
+	testResults, testErr := parseBreseqHtmlFile(strings.NewReader(fixture), breseqVers027Number)
+	assert.Nil(t, testResults)
+	assert.NotNil(t, testErr)
+	assert.Contains(t, testErr.Error(), "12,34x")
+}
@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/bio-pdv/tools/model"
 	"github.com/spf13/cobra"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"runtime"
 	"strings"
+	"syscall"
 )
 
 const (
@@ -23,60 +29,253 @@ const (
 	appVersFlag = "app-version"
 	shortAvFlag = "v"
 
-	defaultParseFileType = "html"
-	defaultParseAppName  = "breseq"
-	defaultParseVersion  = "0.27.*"
-
 	debugFlag      = "debug"
 	shortDebugFlag = "d"
 
-	outputTypeFlag    = "ot"
-	csvOutputType     = "csv"
-	tsvOutputType     = "tsv"
-	defaultOutputType = csvOutputType
-	csvDelimiter      = ","
-	tsvDelimiter      = "\t"
-
 	statusFlag = "status"
+
+	jobsFlag      = "jobs"
+	shortJobsFlag = "j"
+
+	lenientFlag = "lenient"
+
+	filePathsFlagUsage = "Filename(s), directories, or glob patterns to parse. Can be repeated or comma separated. Directories are searched recursively for index.html and *.gd files."
+	lenientFlagUsage   = "Repairs malformed HTML tables the way browsers do, e.g. implicitly closing <td> and <tr> tags left open, reporting each repair as a warning instead of failing the file."
+
+	parseSummaryMsgFmt   = "Parse complete. Files: %d Succeeded: %d Failed: %d Warnings: %d\n"
+	errFindFilesMsgFmt   = "Could not find the files. Error: '%s'\n"
+	parseWarningMsgFmt   = "Repaired the file. Warning: '%s'\n"
+	parsedFileMsgFmt     = "Parsed File: %s Sequence Annotations: %d Duration: %s\n"
+	errInterruptedMsg    = "Interrupted."
+	errWriteOutputMsgFmt = "Could not write the output. Error: '%w'"
+)
+
+const (
+	// Exit codes of the commands. Files that fail to parse exit with the code of the
+	// cause, and any other failure with exitError.
+	exitError             = 1
+	exitFileNotFound      = 2
+	exitUnsupportedFormat = 3
+	exitVersionMismatch   = 4
+	exitMalformedTable    = 5
+	exitHeaderMismatch    = 6
+	exitInterrupted       = 130
 )
 
 var (
-	cmdLog = log.New(os.Stdout, "gene:", log.Ltime)
+	// parseExitCodes maps the causes of parse errors to their exit codes.
+	parseExitCodes = []struct {
+		err  error
+		code int
+	}{
+		{os.ErrNotExist, exitFileNotFound},
+		{parse.ErrUnsupportedFormat, exitUnsupportedFormat},
+		{parse.ErrVersionMismatch, exitVersionMismatch},
+		{parse.ErrMalformedTable, exitMalformedTable},
+		{parse.ErrHeaderMismatch, exitHeaderMismatch},
+	}
+
+	// cmdLog reports the tool's progress on stderr, so it doesn't mix with
+	// the data written to stdout.
+	cmdLog = log.New(os.Stderr, "gene:", log.Ltime)
 )
 
 func init() {
 	// TODO Auto-detection of credentials.
 	rootCmd.PersistentFlags().BoolP(debugFlag, shortDebugFlag, false, "Turns on debug logging.")
 	rootCmd.PersistentFlags().Bool(statusFlag, false, "Turns on reporting of tool progress. ")
+	// Execute prints the errors returned by the commands itself.
+	rootCmd.SilenceErrors = true
 	rootCmd.AddCommand(parseCmd)
 	rootCmd.AddCommand(uploadCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(searchCmd)
 
-	parseCmd.Flags().StringP(fPathFlag, shortFpFlag, "", "Filename to parse.")
-	// TODO List out all available options for these fields in the help
-	parseCmd.Flags().StringP(fTypeFlag, shortfTypeFlag, defaultParseFileType, "File format type of the data.")
-	// If the application or version is not given, then an auto-detection should ensue.
-	// If the auto-detection fails, then we will need to error out.
-	parseCmd.Flags().StringP(appNameFlag, shortAnFlag, defaultParseAppName, "Application that generated the data.")
-	parseCmd.Flags().StringP(appVersFlag, shortAvFlag, defaultParseVersion, "Version of the application that generated the data.")
-	parseCmd.Flags().String(outputTypeFlag, defaultOutputType, "Output type: csv, tsv")
+	parseCmd.Flags().StringSliceP(fPathFlag, shortFpFlag, nil, filePathsFlagUsage)
+	// If the file type, application, or version is not given, then an auto-detection ensues.
+	// If the auto-detection fails, then the parse errors out.
+	fileTypes, appNames, appVersions := parserOptions()
+	parseCmd.Flags().StringP(fTypeFlag, shortfTypeFlag, "", fmt.Sprintf("File format type of the data: %s. Auto-detected if not given.", strings.Join(fileTypes, ", ")))
+	parseCmd.Flags().StringP(appNameFlag, shortAnFlag, "", fmt.Sprintf("Application that generated the data: %s. Auto-detected if not given.", strings.Join(appNames, ", ")))
+	parseCmd.Flags().StringP(appVersFlag, shortAvFlag, "", fmt.Sprintf("Version of the application that generated the data: %s. Auto-detected if not given.", strings.Join(appVersions, ", ")))
+	parseCmd.Long = parseCmd.Long + "\n\nAvailable parsers (file-type/app-name/app-version):\n  " + strings.Join(parserNames(), "\n  ")
+	parseCmd.Flags().IntP(jobsFlag, shortJobsFlag, runtime.NumCPU(), "Number of files parsed concurrently. The output keeps the order of the files.")
+	addOutputFlags(parseCmd)
+	addSampleSheetFlag(parseCmd)
+	addLenientFlag(parseCmd)
 }
 
+// parserOptions lists the unique file types, applications, and versions
+// across all of the registered parsers.
+func parserOptions() ([]string, []string, []string) {
+	fileTypes, appNames, appVersions := []string{}, []string{}, []string{}
+	for _, key := range parse.Parsers() {
+		fileTypes = appendUnique(fileTypes, key.FileType)
+		appNames = appendUnique(appNames, key.Application)
+		appVersions = appendUnique(appVersions, key.Version)
+	}
+	return fileTypes, appNames, appVersions
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func parserNames() []string {
+	keys := parse.Parsers()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	return names
+}
+
+// seqAnnotationFilePaths collects the filepaths given by the command's flag and
+// arguments, expanding directories and glob patterns into the files they hold. The
+// given filepaths that don't name any files are returned as errors, see reportPathErrors.
+func seqAnnotationFilePaths(cmd *cobra.Command, args []string) ([]string, []error) {
+	filePaths, _ := cmd.Flags().GetStringSlice(fPathFlag)
+	filePaths = append(filePaths, args...)
+	if len(filePaths) <= 0 {
+		return filePaths, nil
+	}
+	return parse.FindSeqAnnotationFiles(filePaths)
+}
+
+// reportPathErrors reports each of the given filepaths that didn't name any files on
+// stderr, and tallies it as a failed file, so the rest of the files are still processed.
+func reportPathErrors(cmd *cobra.Command, pathErrs []error, failures *fileFailures) {
+	for _, err := range pathErrs {
+		fmt.Fprintf(cmd.ErrOrStderr(), errFindFilesMsgFmt, err.Error())
+		failures.add(err)
+	}
+}
+
+// parserFlags reads the file type, application, and version flags, which are left
+// empty to be auto-detected.
+func parserFlags(cmd *cobra.Command) (string, string, string, error) {
+	fType, fErr := cmd.Flags().GetString(fTypeFlag)
+	appName, anErr := cmd.Flags().GetString(appNameFlag)
+	appVers, avErr := cmd.Flags().GetString(appVersFlag)
+	for _, err := range []error{fErr, anErr, avErr} {
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+	return fType, appName, appVers, nil
+}
+
+func addLenientFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(lenientFlag, false, lenientFlagUsage)
+}
+
+// parseOptions reads the parse options given by the command's flags. Each warning
+// is reported on stderr as it's found.
+func parseOptions(cmd *cobra.Command) parse.Options {
+	lenient, _ := cmd.Flags().GetBool(lenientFlag)
+	return parse.Options{
+		Lenient: lenient,
+		OnWarning: func(warning *parse.ParseError) {
+			fmt.Fprintf(cmd.ErrOrStderr(), parseWarningMsgFmt, warning.Error())
+		},
+	}
+}
+
+// exitCode picks the exit code for the error of parsing a file.
+func exitCode(err error) int {
+	for _, c := range parseExitCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return exitError
+}
+
+// fileFailures tallies the files a command failed on, keeping the exit code of the first.
+type fileFailures struct {
+	count      int
+	exitStatus int
+}
+
+func (f *fileFailures) add(err error) {
+	if f.count == 0 {
+		f.exitStatus = exitCode(err)
+	}
+	f.count++
+}
+
+// err returns an error with the exit code of the first failure, if there were any.
+// Each failure was already reported, so there's no message to print.
+func (f *fileFailures) err() error {
+	if f.count > 0 {
+		return &commandError{code: f.exitStatus}
+	}
+	return nil
+}
+
+// commandError is returned by a command to exit with a code other than exitError.
+// The message is printed, unless it's empty because the cause was already reported.
+type commandError struct {
+	code int
+	err  error
+}
+
+func (e *commandError) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// exitWith returns the error wrapped to exit with the code.
+func exitWith(code int, err error) error {
+	return &commandError{code: code, err: err}
+}
+
+// Execute runs the command given on the command line, then exits with the code of its
+// error, if there's one. The commands return their errors rather than exiting, so the
+// stores they open are closed first.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		if msg := err.Error(); msg != "" {
+			fmt.Fprintln(rootCmd.ErrOrStderr(), msg)
+		}
+		os.Exit(commandExitCode(err))
+	}
+}
+
+// commandExitCode picks the exit code for the error returned by a command.
+func commandExitCode(err error) int {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.code
 	}
+	return exitError
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "gene",
 	Short: "Gene is a devops data tool set for the bio-pdv service.",
 	Long: `Gene is a devops data tool set for performing CRUD operations 
-on the bio-pdv service's database.`,
+on the bio-pdv service's database.
+
+Exit codes: 0 on success, 1 on a general failure, 2 when a file isn't found,
+3 for an unsupported file format, 4 for an application version mismatch, 5 for
+a malformed table, 6 for a table header mismatch, and 130 when interrupted. When
+several files fail to parse, the code is that of the first.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// The flags parsed, so any error from here on isn't a usage error.
+		cmd.SilenceUsage = true
 		debug, err := cmd.Flags().GetBool(debugFlag)
 		if err == nil && !debug {
 			log.SetOutput(ioutil.Discard)
@@ -85,97 +284,99 @@ on the bio-pdv service's database.`,
 		status, err := cmd.Flags().GetBool(statusFlag)
 		if err != nil || !status {
 			cmdLog.SetOutput(ioutil.Discard)
+		} else {
+			cmdLog.SetOutput(cmd.ErrOrStderr())
 		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Gene Root Command")
+		fmt.Fprintln(cmd.OutOrStdout(), "Gene Root Command")
 	},
 }
 
 var parseCmd = &cobra.Command{
-	Use:   "parse",
-	Short: "Parses a sequence annotation file.",
-	Long: `Parses a sequence annotation file into a requested format.
-The only supported input file format is HTML.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:   "parse [filepath...]",
+	Short: "Parses sequence annotation file(s).",
+	Long: `Parses sequence annotation file(s) into a requested format.
+Filepaths are given as arguments or with --filepath, and can be files, directories
+searched recursively for index.html and *.gd files, or glob patterns. Each file's
+sequence annotations are tagged with its filepath in the source field. Files that
+fail to parse, and filepaths that don't name any files, are reported on stderr,
+followed by a summary, and the rest are still written. Files are parsed concurrently, up to --jobs at a time, and written
+in order as they finish. The file type, application, and version are auto-detected unless given.
+With --lenient, malformed HTML tables are repaired rather than failing the file, and
+each repair is reported on stderr as a warning.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cmdLog.Println("Parsing...")
-		filePath, err := cmd.Flags().GetString(fPathFlag)
-		if filePath == "" || err != nil {
-			fmt.Println("Filepath is required.")
-			return
+		filePaths, pathErrs := seqAnnotationFilePaths(cmd, args)
+		if len(filePaths) <= 0 && len(pathErrs) <= 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "At least one filepath is required.")
+			return nil
 		}
 
-		fType, fErr := cmd.Flags().GetString(fTypeFlag)
-		appName, anErr := cmd.Flags().GetString(appNameFlag)
-		appVers, avErr := cmd.Flags().GetString(appVersFlag)
-		isErred := fErr != nil || anErr != nil || avErr != nil
-		if isErred || fType != defaultParseFileType || appName != defaultParseAppName || appVers != defaultParseVersion {
-			fmt.Printf("Only supported file is, %s: '%s' %s: '%s' %s: '%s'", fTypeFlag, defaultParseFileType, appNameFlag, defaultParseAppName, appVersFlag, defaultParseVersion)
+		fType, appName, appVers, err := parserFlags(cmd)
+		if err != nil {
+			return err
 		}
 
-		cmdLog.Printf("Parsing File: %s\n", filePath)
-		results, err := parse.ParseSeqAnnotationDataFilePath(filePath, "html", "breseq", "0.27")
+		sheet, err := readSampleSheet(cmd)
 		if err != nil {
-			fmt.Printf("Could not parse the file. Error: '%s'\n", err.Error())
+			return err
 		}
 
-		delim := csvDelimiter
-		outputType, _ := cmd.Flags().GetString(outputTypeFlag)
-		if outputType == tsvOutputType {
-			delim = tsvDelimiter
-		}
-		for i, collection := range results {
-			fmt.Printf("Collection %d\n", i)
-			for j, sa := range collection {
-				saString := []string{
-					fmt.Sprintf("%d", j),
-					sa.SequenceId,
-					sa.Position,
-					sa.Mutation,
-					sa.Frequency,
-					sa.Annotation,
-					sa.Gene,
-					sa.Description,
+		jobs, _ := cmd.Flags().GetInt(jobsFlag)
+		opts := parseOptions(cmd)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Each file's results are written as soon as it and the files before it are
+		// parsed. Errors go to stderr, so they don't mix with the output on stdout.
+		failures, warnings := &fileFailures{}, 0
+		reportPathErrors(cmd, pathErrs, failures)
+		err = streamSeqAnnotations(cmd, func(write func([]model.SequenceAnnotation) error) error {
+			// Stops the parsing if the output can't be written.
+			parseCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			for result := range parse.ParseFiles(parseCtx, filePaths, jobs, fType, appName, appVers, opts) {
+				for _, warning := range result.Warnings {
+					opts.OnWarning(warning)
 				}
-				fmt.Println(strings.Join(saString, delim))
-			}
-		}
-	},
-}
+				warnings += len(result.Warnings)
 
-var uploadCmd = &cobra.Command{
-	Use:   "upload",
-	Short: "Uploads sequence annotation file(s) to the database.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Upload Command")
-		// TODO Batching option
-	},
-}
+				if result.Err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the file. Error: '%s'\n", result.Err.Error())
+					failures.add(result.Err)
+					continue
+				}
 
-var updateCmd = &cobra.Command{
-	Use:   "update",
-	Short: "Updates sequence annotation records in the database.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Update Command")
-		// TODO Batching option
-	},
-}
+				if err := stampSample(sheet, result.FilePath, result.Results); err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), err)
+					failures.add(err)
+					continue
+				}
 
-var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Deletes sequence annotation records from the database.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Delete Command")
-		// TODO Batching option
-		// TODO Fail Safe
-	},
-}
+				sas := 0
+				for _, collection := range result.Results {
+					if err := write(collection); err != nil {
+						return err
+					}
+					sas += len(collection)
+				}
+				cmdLog.Printf(parsedFileMsgFmt, result.FilePath, sas, result.Duration)
+			}
+			return ctx.Err()
+		})
 
-var searchCmd = &cobra.Command{
-	Use:   "search",
-	Short: "Search for sequence annotation records in the database or file.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Search Command")
-		// TODO Paginate data
+		if ctx.Err() != nil {
+			return exitWith(exitInterrupted, errors.New(errInterruptedMsg))
+		} else if err != nil {
+			return fmt.Errorf(errWriteOutputMsgFmt, err)
+		}
+
+		files := len(filePaths) + len(pathErrs)
+		if files > 1 || failures.count > 0 || warnings > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), parseSummaryMsgFmt, files, files-failures.count, failures.count, warnings)
+		}
+		return failures.err()
 	},
 }
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// executeCommand runs the root command with the arguments, as if from the command line,
+// feeding it stdin and capturing its stdout and stderr.
+func executeCommand(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	err := rootCmd.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+// resetFlags sets the flags of the command, and its sub commands, back to their defaults,
+// since the commands are kept between executions. Slice flags keep whether they were
+// set inside their values, so they're given new values instead.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		defaults := []string{}
+		if trimmed := strings.Trim(f.DefValue, "[]"); trimmed != "" {
+			defaults = strings.Split(trimmed, ",")
+		}
+
+		fresh := pflag.NewFlagSet(f.Name, pflag.ContinueOnError)
+		switch f.Value.Type() {
+		case "stringSlice":
+			fresh.StringSlice(f.Name, defaults, f.Usage)
+			f.Value = fresh.Lookup(f.Name).Value
+		case "stringArray":
+			fresh.StringArray(f.Name, defaults, f.Usage)
+			f.Value = fresh.Lookup(f.Name).Value
+		default:
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+// writeTestFile writes the data to a file in the directory, returning its filepath.
+func writeTestFile(t *testing.T, dir string, name string, data string) string {
+	filePath := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(data), 0600))
+	return filePath
+}
+
+func TestExitCode(t *testing.T) {
+	_, notFoundErr := os.Open(filepath.Join(t.TempDir(), "missing.gd"))
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"Not Found", &parse.ParseError{FilePath: "missing.gd", Err: notFoundErr}, exitFileNotFound},
+		{"Not Found Sentinel", &parse.ParseError{Err: os.ErrNotExist}, exitFileNotFound},
+		{"Not Found Wrapped", fmt.Errorf("outer: %w", &parse.ParseError{Err: notFoundErr}), exitFileNotFound},
+		{"Unsupported Format", &parse.ParseError{Err: fmt.Errorf("vcf: %w", parse.ErrUnsupportedFormat)}, exitUnsupportedFormat},
+		{"Version Mismatch", &parse.ParseError{Err: fmt.Errorf("0.99: %w", parse.ErrVersionMismatch)}, exitVersionMismatch},
+		{"Malformed Table", &parse.ParseError{Table: 1, Row: 2, Err: parse.ErrMalformedTable}, exitMalformedTable},
+		{"Header Mismatch", &parse.ParseError{Err: fmt.Errorf("freq: %w", parse.ErrHeaderMismatch)}, exitHeaderMismatch},
+		{"Unwrapped Sentinel", parse.ErrHeaderMismatch, exitHeaderMismatch},
+		{"Other Parse Error", &parse.ParseError{Err: errors.New("other")}, exitError},
+		{"Other", errors.New("other"), exitError},
+		{"Canceled", context.Canceled, exitError},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, exitCode(c.err), c.name)
+	}
+}
+
+func TestFileFailures(t *testing.T) {
+	failures := &fileFailures{}
+	assert.Nil(t, failures.err())
+
+	failures.add(&parse.ParseError{Err: parse.ErrMalformedTable})
+	failures.add(&parse.ParseError{Err: os.ErrNotExist})
+	failures.add(errors.New("other"))
+	assert.Equal(t, 3, failures.count)
+
+	// The first failure's code is kept, and its message was already reported.
+	err := failures.err()
+	assert.NotNil(t, err)
+	assert.Equal(t, "", err.Error())
+	assert.Equal(t, exitMalformedTable, commandExitCode(err))
+
+	// A general failure first is kept too, rather than replaced by a later specific one.
+	failures = &fileFailures{}
+	failures.add(errors.New("other"))
+	failures.add(&parse.ParseError{Err: parse.ErrUnsupportedFormat})
+	assert.Equal(t, exitError, commandExitCode(failures.err()))
+}
+
+func TestCommandExitCode(t *testing.T) {
+	cause := errors.New("cause")
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+		msg      string
+	}{
+		{"Exit With", exitWith(exitInterrupted, cause), exitInterrupted, "cause"},
+		{"Wrapped Exit With", fmt.Errorf("outer: %w", exitWith(exitFileNotFound, cause)), exitFileNotFound, "outer: cause"},
+		{"Plain", cause, exitError, "cause"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, commandExitCode(c.err), c.name)
+		assert.Equal(t, c.msg, c.err.Error(), c.name)
+		assert.True(t, errors.Is(c.err, cause), c.name)
+	}
+}
+
+func TestParseCmdExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	gdPath := writeTestFile(t, dir, "output.gd", testGdHeader+testGdData)
+	unknownPath := writeTestFile(t, dir, "unknown.txt", "not a genome diff\n")
+	malformedPath := writeTestFile(t, dir, "malformed.gd", testGdHeader+"SNP\t1\t.\tREL606\n")
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"Unsupported", []string{unknownPath}, exitUnsupportedFormat},
+		{"Malformed", []string{malformedPath}, exitMalformedTable},
+		{"First Failure", []string{gdPath, unknownPath, malformedPath, "--jobs", "1"}, exitUnsupportedFormat},
+		{"First Failure Reversed", []string{gdPath, malformedPath, unknownPath, "--jobs", "1"}, exitMalformedTable},
+	}
+
+	for _, c := range cases {
+		_, stderr, err := executeCommand(t, "", append([]string{"parse"}, c.args...)...)
+		assert.NotNil(t, err, c.name)
+		assert.Equal(t, c.expected, commandExitCode(err), c.name)
+		assert.Contains(t, stderr, "Could not parse the file.", c.name)
+	}
+
+	// A file of another version than the one given is a mismatch, whichever flags are given.
+	htmlPath := filepath.Join("parse", "testdata", "breseq", "polymorphism.html")
+	for _, args := range [][]string{
+		{htmlPath, "-v", "0.27"},
+		{htmlPath, "-a", "breseq", "-v", "0.27"},
+		{htmlPath, "-t", "html", "-a", "breseq", "-v", "0.27"},
+		{gdPath, "-v", "0.27"},
+		{gdPath, "-t", "gd", "-a", "breseq", "-v", "0.27"},
+	} {
+		_, stderr, err := executeCommand(t, "", append([]string{"parse"}, args...)...)
+		assert.NotNil(t, err, args)
+		assert.Equal(t, exitVersionMismatch, commandExitCode(err), args)
+		assert.Contains(t, stderr, "Expected a breseq 0.27.* ", args)
+		assert.NotContains(t, stderr, "Available parsers", args)
+	}
+
+}
+
+func TestParseCmdPathErrors(t *testing.T) {
+	dir := t.TempDir()
+	gdPath := writeTestFile(t, dir, "output.gd", testGdData)
+	missingPath, patternPath := filepath.Join(dir, "missing.gd"), filepath.Join(dir, "*.html")
+
+	// Filepaths that don't name any files fail like files that don't parse, and the rest are still parsed.
+	stdout, stderr, err := executeCommand(t, "", "parse", missingPath, gdPath, patternPath, "--columns", "position,source", "--header=false")
+	assert.NotNil(t, err)
+	assert.Equal(t, exitFileNotFound, commandExitCode(err))
+	assert.Equal(t, [][]string{
+		{"1,000", gdPath},
+		{"2,000", gdPath},
+		{"3,000", gdPath},
+		{"4,000", gdPath},
+		{"5,000", gdPath},
+	}, readDelimited(t, stdout, csvDelimiter))
+	assert.Contains(t, stderr, fmt.Sprintf("Could not find the files. Error: 'No such file or directory: '%s''\n", missingPath))
+	assert.Contains(t, stderr, fmt.Sprintf("Could not find the files. Error: 'No files match the pattern: '%s''\n", patternPath))
+	assert.Contains(t, stderr, "Parse complete. Files: 3 Succeeded: 1 Failed: 2 Warnings: 0\n")
+
+	_, stderr, err = executeCommand(t, "", "parse", missingPath)
+	assert.Equal(t, exitFileNotFound, commandExitCode(err))
+	assert.Contains(t, stderr, "Parse complete. Files: 1 Succeeded: 0 Failed: 1 Warnings: 0\n")
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/bio-pdv/tools/model"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sampleSheetFlag      = "sample-sheet"
+	sampleSheetFlagUsage = "CSV or TSV file mapping each filepath to its sample's population, replicate, generation, treatment, and time point. Relative filepaths are relative to the sample sheet."
+)
+
+func addSampleSheetFlag(cmd *cobra.Command) {
+	cmd.Flags().String(sampleSheetFlag, "", sampleSheetFlagUsage)
+}
+
+// readSampleSheet reads the sample sheet given by the command's flags. Returns
+// a nil sheet if none is given.
+func readSampleSheet(cmd *cobra.Command) (parse.SampleSheet, error) {
+	sheetPath, _ := cmd.Flags().GetString(sampleSheetFlag)
+	if sheetPath == "" {
+		return nil, nil
+	}
+
+	cmdLog.Printf("Reading Sample Sheet: %s\n", sheetPath)
+	return parse.ReadSampleSheetFilePath(sheetPath)
+}
+
+// stampSample stamps the sequence annotations parsed from the file with its sample
+// in the sheet, if there's a sheet. Returns an error if the file isn't in the sheet.
+func stampSample(sheet parse.SampleSheet, filePath string, results [][]model.SequenceAnnotation) error {
+	if sheet == nil {
+		return nil
+	}
+
+	sample, err := sheet.Lookup(filePath)
+	if err != nil {
+		return err
+	}
+
+	sample.Stamp(results)
+	return nil
+}
+
+// sampleStamper looks up the file's sample in the sheet, returning a function stamping
+// each sequence annotation parsed from it, which does nothing if there's no sheet.
+// Returns an error if the file isn't in the sheet.
+func sampleStamper(sheet parse.SampleSheet, filePath string) (func(sa *model.SequenceAnnotation), error) {
+	if sheet == nil {
+		return func(*model.SequenceAnnotation) {}, nil
+	}
+
+	sample, err := sheet.Lookup(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return sample.StampSeqAnnotation, nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/store"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+const (
+	pageSizeFlag    = "page-size"
+	defaultPageSize = 100
+	cursorFlag      = "cursor"
+
+	errInvalidCursorMsgFmt   = "Invalid cursor: '%s'. Expected the cursor printed by the previous page."
+	errInvalidPageSizeMsgFmt = "Page size must be greater than 0, but got: %d"
+	errSearchMsgFmt          = "Could not search for sequence annotations. Error: '%w'"
+	nextCursorMsgFmt         = "Next cursor: %s\n"
+)
+
+func init() {
+	searchCmd.Flags().StringP(fPathFlag, shortFpFlag, "", "Filename to search instead of the database.")
+	searchCmd.Flags().StringP(fTypeFlag, shortfTypeFlag, "", "File format type of the file. Auto-detected if not given.")
+	searchCmd.Flags().StringP(appNameFlag, shortAnFlag, "", "Application that generated the file. Auto-detected if not given.")
+	searchCmd.Flags().StringP(appVersFlag, shortAvFlag, "", "Version of the application that generated the file. Auto-detected if not given.")
+	searchCmd.Flags().Int(pageSizeFlag, defaultPageSize, "Maximum number of sequence annotations printed per page.")
+	searchCmd.Flags().String(cursorFlag, "", "Cursor of the page to print, as printed by the previous page. Prints the first page if not given.")
+	addLenientFlag(searchCmd)
+	addOutputFlags(searchCmd)
+	addFilterFlags(searchCmd)
+	addStoreFlags(searchCmd)
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Search for sequence annotation records in the database or file.",
+	Long: `Search for sequence annotation records in the database or file.
+Prints a page of the sequence annotations matching every given filter, in the
+same format as the parse command. If there are more matches, the cursor of the
+next page is printed to stderr, so it can be passed back with --cursor.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := newSeqAnnotationFilter(cmd)
+		if err != nil {
+			return err
+		}
+
+		pageSize, _ := cmd.Flags().GetInt(pageSizeFlag)
+		cursor, _ := cmd.Flags().GetString(cursorFlag)
+		filePath, _ := cmd.Flags().GetString(fPathFlag)
+
+		var page []model.SequenceAnnotation
+		var nextCursor string
+		if filePath != "" {
+			page, nextCursor, err = searchFile(cmd, filePath, filter, pageSize, cursor)
+		} else {
+			page, nextCursor, err = searchStore(cmd, filter, pageSize, cursor)
+		}
+
+		if err != nil {
+			return fmt.Errorf(errSearchMsgFmt, err)
+		}
+
+		if err = printSeqAnnotations(cmd, [][]model.SequenceAnnotation{page}); err != nil {
+			return fmt.Errorf(errWriteOutputMsgFmt, err)
+		}
+		if nextCursor != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), nextCursorMsgFmt, nextCursor)
+		}
+		return nil
+	},
+}
+
+func searchFile(cmd *cobra.Command, filePath string, filter *store.Filter, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error) {
+	fType, appName, appVers, err := parserFlags(cmd)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cmdLog.Printf("Searching File: %s\n", filePath)
+	results, err := parse.ParseSeqAnnotationDataFilePath(filePath, fType, appName, appVers, parseOptions(cmd))
+	if err != nil {
+		return nil, "", err
+	}
+
+	matched := []model.SequenceAnnotation{}
+	for _, collection := range results {
+		for _, sa := range collection {
+			if filter.Matches(sa) {
+				matched = append(matched, sa)
+			}
+		}
+	}
+	return pageSeqAnnotations(matched, pageSize, cursor)
+}
+
+func searchStore(cmd *cobra.Command, filter *store.Filter, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error) {
+	st, err := openStore(cmd)
+	if err != nil {
+		return nil, "", err
+	}
+	defer st.Close()
+
+	cmdLog.Printf("Searching store: %s\n", st.Name())
+	return st.Find(context.Background(), filter, pageSize, cursor)
+}
+
+// pageSeqAnnotations slices out a page of at most pageSize sequence annotations. The
+// cursor is the offset of the page, or empty for the first page.
+//
+// Returns the page along with the cursor of the next page, which is empty on the last page.
+func pageSeqAnnotations(sas []model.SequenceAnnotation, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf(errInvalidPageSizeMsgFmt, pageSize)
+	}
+
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			return nil, "", fmt.Errorf(errInvalidCursorMsgFmt, cursor)
+		}
+	}
+
+	if offset > len(sas) {
+		offset = len(sas)
+	}
+
+	end := offset + pageSize
+	if end >= len(sas) {
+		return sas[offset:], "", nil
+	}
+	return sas[offset:end], strconv.Itoa(end), nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageSeqAnnotations(t *testing.T) {
+	sas := []model.SequenceAnnotation{{Position: "1"}, {Position: "2"}, {Position: "3"}, {Position: "4"}, {Position: "5"}}
+	cases := []struct {
+		name       string
+		pageSize   int
+		cursor     string
+		expected   []model.SequenceAnnotation
+		nextCursor string
+	}{
+		{"First Page", 2, "", sas[0:2], "2"},
+		{"Middle Page", 2, "2", sas[2:4], "4"},
+		{"Last Page", 2, "4", sas[4:], ""},
+		{"Exactly One Page", 5, "", sas, ""},
+		{"Page Size Larger Than Results", 10, "", sas, ""},
+		{"Last Page Ending On Page Size", 3, "2", sas[2:], ""},
+		{"Cursor Past The End", 2, "10", sas[5:], ""},
+	}
+
+	for _, c := range cases {
+		page, nextCursor, err := pageSeqAnnotations(sas, c.pageSize, c.cursor)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.expected, page, c.name)
+		assert.Equal(t, c.nextCursor, nextCursor, c.name)
+	}
+}
+
+func TestPageSeqAnnotationsInvalid(t *testing.T) {
+	sas := []model.SequenceAnnotation{{Position: "1"}, {Position: "2"}}
+	cases := []struct {
+		name     string
+		pageSize int
+		cursor   string
+	}{
+		{"Bad Cursor", 2, "abc"},
+		{"Negative Cursor", 2, "-1"},
+		{"Zero Page Size", 0, ""},
+		{"Negative Page Size", -1, ""},
+	}
+
+	for _, c := range cases {
+		page, nextCursor, err := pageSeqAnnotations(sas, c.pageSize, c.cursor)
+		assert.NotNil(t, err, c.name)
+		assert.Nil(t, page, c.name)
+		assert.Equal(t, "", nextCursor, c.name)
+	}
+}
+
+func TestSearchCmdFile(t *testing.T) {
+	filePath := writeTestFile(t, t.TempDir(), "output.gd", testGdData)
+	cases := []struct {
+		name       string
+		args       []string
+		expected   []string
+		nextCursor string
+	}{
+		{"All", []string{}, []string{"1000", "2000", "3000", "4000", "5000"}, ""},
+		{"First Page", []string{"--page-size", "2"}, []string{"1000", "2000"}, "2"},
+		{"Next Page", []string{"--page-size", "2", "--cursor", "2"}, []string{"3000", "4000"}, "4"},
+		{"Last Page", []string{"--page-size", "2", "--cursor", "4"}, []string{"5000"}, ""},
+		{"Mutation Type Filter", []string{"--mutation-type", "SNP"}, []string{"1000", "2000"}, ""},
+		{"Position Filter", []string{"--min-position", "2000", "--max-position", "4000"}, []string{"2000", "3000", "4000"}, ""},
+		{"Filtered Page", []string{"--min-position", "2000", "--page-size", "2", "--cursor", "2"}, []string{"4000", "5000"}, ""},
+		{"No Matches", []string{"--seq-id", "REL607"}, []string{}, ""},
+	}
+
+	for _, c := range cases {
+		args := append([]string{"search", "-f", filePath, "--columns", "position_value", "--header=false"}, c.args...)
+		stdout, stderr, err := executeCommand(t, "", args...)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.expected, strings.Fields(stdout), c.name)
+		if c.nextCursor != "" {
+			assert.Equal(t, "Next cursor: "+c.nextCursor+"\n", stderr, c.name)
+		} else {
+			assert.Equal(t, "", stderr, c.name)
+		}
+	}
+}
+
+func TestSearchCmdFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdData)
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"Bad Cursor", []string{"-f", filePath, "--cursor", "abc"}},
+		{"Zero Page Size", []string{"-f", filePath, "--page-size", "0"}},
+		{"Unknown Mutation Type", []string{"-f", filePath, "--mutation-type", "unknown"}},
+		{"Missing File", []string{"-f", filepath.Join(dir, "missing.gd")}},
+	}
+
+	for _, c := range cases {
+		stdout, _, err := executeCommand(t, "", append([]string{"search"}, c.args...)...)
+		assert.NotNil(t, err, c.name)
+		assert.Equal(t, exitError, commandExitCode(err), c.name)
+		assert.Equal(t, "", stdout, c.name)
+	}
+}
+
+func TestSearchCmdStore(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdData)
+	storePath := filepath.Join(dir, "local.db")
+	_, _, err := executeCommand(t, "", "upload", "--local-store", storePath, filePath)
+	assert.Nil(t, err)
+
+	// Follows the printed cursors until the last page.
+	positions, cursor, pages := []string{}, "", 0
+	for pages = 1; pages <= 5; pages++ {
+		args := []string{"search", "--local-store", storePath, "--min-position", "2000", "--page-size", "2", "--columns", "position_value", "--header=false"}
+		if cursor != "" {
+			args = append(args, "--cursor", cursor)
+		}
+
+		stdout, stderr, err := executeCommand(t, "", args...)
+		assert.Nil(t, err)
+		positions = append(positions, strings.Fields(stdout)...)
+		cursor = strings.TrimSpace(strings.TrimPrefix(stderr, "Next cursor:"))
+		if cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, []string{"2000", "3000", "4000", "5000"}, positions)
+
+	_, _, err = executeCommand(t, "", "search", "--local-store", storePath, "--cursor", "not a cursor")
+	assert.NotNil(t, err)
+}
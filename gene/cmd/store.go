@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"github.com/bio-pdv/tools/store"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+const (
+	localStoreFlag    = "local-store"
+	mongoUriFlag      = "mongo-uri"
+	defaultMongoUri   = "mongodb://localhost:27017"
+	databaseFlag      = "database"
+	defaultDatabase   = "bio-pdv"
+	collectionFlag    = "collection"
+	defaultCollection = "sequence_annotations"
+	timeoutFlag       = "timeout"
+	defaultTimeout    = 30 * time.Second
+
+	batchSizeFlag = "batch-size"
+)
+
+// addStoreFlags adds the flags for opening the store holding the sequence annotations.
+func addStoreFlags(cmd *cobra.Command) {
+	cmd.Flags().String(localStoreFlag, "", "Local file to store the sequence annotations in, instead of MongoDB. Created if it doesn't exist.")
+	cmd.Flags().String(mongoUriFlag, defaultMongoUri, "MongoDB connection string.")
+	cmd.Flags().String(databaseFlag, defaultDatabase, "Database holding the sequence annotations.")
+	cmd.Flags().String(collectionFlag, defaultCollection, "Collection holding the sequence annotations.")
+	cmd.Flags().Duration(timeoutFlag, defaultTimeout, "Timeout for connecting to the database.")
+}
+
+// openStore opens the local store file if one is given by the command's flags,
+// otherwise connects to the MongoDB collection. The caller is responsible for
+// closing the returned store.
+func openStore(cmd *cobra.Command) (store.Store, error) {
+	opts := store.Options{BatchSize: store.DefaultBatchSize}
+	if cmd.Flags().Lookup(batchSizeFlag) != nil {
+		opts.BatchSize, _ = cmd.Flags().GetInt(batchSizeFlag)
+	}
+
+	localPath, _ := cmd.Flags().GetString(localStoreFlag)
+	if localPath != "" {
+		cmdLog.Printf("Opening local store: %s\n", localPath)
+		return store.OpenLocal(localPath, opts)
+	}
+
+	uri, _ := cmd.Flags().GetString(mongoUriFlag)
+	database, _ := cmd.Flags().GetString(databaseFlag)
+	collection, _ := cmd.Flags().GetString(collectionFlag)
+	timeout, _ := cmd.Flags().GetDuration(timeoutFlag)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmdLog.Printf("Connecting to: %s\n", uri)
+	st, err := store.OpenMongo(ctx, uri, database, collection, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdLog.Printf("Using collection: %s\n", st.Name())
+	return st, nil
+}
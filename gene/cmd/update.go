@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/store"
+	"github.com/spf13/cobra"
+)
+
+const (
+	setFlag                = "set"
+	patchAllFlagUsage      = "Allows patching every sequence annotation in the collection when no filter is given."
+	patchSetFlagUsage      = "Field to set as 'Field=value', e.g. 'Generation=500'. Can be repeated. When re-uploading, sets the field on every parsed sequence annotation."
+	updateSummaryMsgFmt    = "Update complete. Matched: %d Modified: %d Upserted: %d\n"
+	reuploadedFileMsgFmt   = "Re-uploaded File: %s Matched: %d Modified: %d Upserted: %d\n"
+	errNoUpdateMsg         = "Either filepath(s) to re-upload or at least one --set field to patch is required."
+	errUnfilteredPatchMsg  = "Refusing to patch every sequence annotation in the collection without a filter. Use --all to override."
+	errFilteredReuploadMsg = "Filters cannot be combined with re-uploading file(s)."
+	errSampleSheetPatchMsg = "A sample sheet can only be given when re-uploading file(s)."
+	errPatchMsgFmt         = "Could not patch the sequence annotations. Error: '%w'"
+)
+
+func init() {
+	updateCmd.Flags().StringSliceP(fPathFlag, shortFpFlag, nil, "Filename(s), directories, or glob patterns to re-upload. Can be repeated or comma separated. Directories are searched recursively for index.html and *.gd files.")
+	updateCmd.Flags().StringP(fTypeFlag, shortfTypeFlag, "", "File format type of the data. Auto-detected if not given.")
+	updateCmd.Flags().StringP(appNameFlag, shortAnFlag, "", "Application that generated the data. Auto-detected if not given.")
+	updateCmd.Flags().StringP(appVersFlag, shortAvFlag, "", "Version of the application that generated the data. Auto-detected if not given.")
+	updateCmd.Flags().StringArray(setFlag, nil, patchSetFlagUsage)
+	updateCmd.Flags().Bool(allFlag, false, patchAllFlagUsage)
+	updateCmd.Flags().Int(batchSizeFlag, store.DefaultBatchSize, "Number of sequence annotations updated per database request.")
+	addLenientFlag(updateCmd)
+	addFilterFlags(updateCmd)
+	addSampleSheetFlag(updateCmd)
+	addStoreFlags(updateCmd)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update [filepath...]",
+	Short: "Updates sequence annotation records in the database.",
+	Long: `Updates sequence annotation records in the database.
+Given filepath(s), re-uploads the re-run sequence annotation file(s), replacing
+each stored record with the same sequence id, position, mutation, generation,
+population, replicate, and time point, or inserting it if there's none.
+
+Otherwise, patches the fields given by --set on every record matching the same
+filters as the search command. Patching the whole collection requires --all.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePaths, pathErrs := seqAnnotationFilePaths(cmd, args)
+		reupload := len(filePaths) > 0 || len(pathErrs) > 0
+		assignments, _ := cmd.Flags().GetStringArray(setFlag)
+		if !reupload && len(assignments) <= 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), errNoUpdateMsg)
+			return nil
+		}
+
+		patch, err := store.ParsePatch(assignments)
+		if err != nil {
+			return err
+		}
+
+		filter, err := newSeqAnnotationFilter(cmd)
+		if err != nil {
+			return err
+		}
+
+		all, _ := cmd.Flags().GetBool(allFlag)
+		if reupload && (!filter.IsEmpty() || all) {
+			return errors.New(errFilteredReuploadMsg)
+		} else if !reupload && filter.IsEmpty() && !all {
+			return errors.New(errUnfilteredPatchMsg)
+		} else if !reupload && !filter.IsEmpty() && all {
+			return errors.New(errFilteredAllMsg)
+		}
+
+		sheet, err := readSampleSheet(cmd)
+		if err != nil {
+			return err
+		} else if sheet != nil && !reupload {
+			return errors.New(errSampleSheetPatchMsg)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		ctx := context.Background()
+		if !reupload {
+			result, err := st.Patch(ctx, filter, patch)
+			fmt.Fprintf(cmd.OutOrStdout(), updateSummaryMsgFmt, result.Matched, result.Modified, result.Upserted)
+			if err != nil {
+				return fmt.Errorf(errPatchMsgFmt, err)
+			}
+			return nil
+		}
+
+		fType, appName, appVers, err := parserFlags(cmd)
+		if err != nil {
+			return err
+		}
+		opts := parseOptions(cmd)
+
+		total, failures := store.UpdateResult{}, &fileFailures{}
+		reportPathErrors(cmd, pathErrs, failures)
+		for _, filePath := range filePaths {
+			cmdLog.Printf("Parsing File: %s\n", filePath)
+			results, err := parse.ParseSeqAnnotationDataFilePath(filePath, fType, appName, appVers, opts)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the file. Error: '%s'\n", err.Error())
+				failures.add(err)
+				continue
+			}
+
+			if err = stampSample(sheet, filePath, results); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+				failures.add(err)
+				continue
+			}
+
+			sas := []model.SequenceAnnotation{}
+			for _, collection := range results {
+				for _, sa := range collection {
+					patch.Apply(&sa)
+					sas = append(sas, sa)
+				}
+			}
+
+			cmdLog.Printf("Re-uploading %d sequence annotations from: %s\n", len(sas), filePath)
+			result, err := st.Upsert(ctx, sas)
+			total.Add(result)
+			cmdLog.Printf(reuploadedFileMsgFmt, filePath, result.Matched, result.Modified, result.Upserted)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Could not re-upload the file. Filepath: '%s' Error: '%s'\n", filePath, err.Error())
+				failures.add(err)
+			}
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), updateSummaryMsgFmt, total.Matched, total.Modified, total.Upserted)
+		return failures.err()
+	},
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/store"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// findStored finds every sequence annotation in the local store matching the filter.
+func findStored(t *testing.T, storePath string, filter *store.Filter) []model.SequenceAnnotation {
+	st, err := store.OpenLocal(storePath, store.Options{BatchSize: store.DefaultBatchSize})
+	assert.Nil(t, err)
+	defer st.Close()
+
+	sas, _, err := st.Find(context.Background(), filter, 100, "")
+	assert.Nil(t, err)
+	return sas
+}
+
+func TestUpdateCmdPatch(t *testing.T) {
+	storePath := uploadTestStore(t)
+	args := []string{"update", "--local-store", storePath, "--mutation-type", "snp", "--set", "Generation=500", "--set", "population=Ara-1"}
+
+	// Patching the same values again matches without modifying anything.
+	for _, expected := range []string{"Matched: 2 Modified: 2 Upserted: 0", "Matched: 2 Modified: 0 Upserted: 0"} {
+		stdout, _, err := executeCommand(t, "", args...)
+		assert.Nil(t, err)
+		assert.Equal(t, "Update complete. "+expected+"\n", stdout)
+	}
+
+	patched := findStored(t, storePath, &store.Filter{Generation: "500"})
+	assert.Equal(t, 2, len(patched))
+	for _, sa := range patched {
+		assert.Equal(t, model.SnpMutation, sa.Variant.Type)
+		assert.Equal(t, "Ara-1", sa.Population)
+	}
+	assert.Equal(t, 5, len(findStored(t, storePath, &store.Filter{})))
+}
+
+func TestUpdateCmdPatchAll(t *testing.T) {
+	storePath := uploadTestStore(t)
+	stdout, _, err := executeCommand(t, "", "update", "--local-store", storePath, "--all", "--set", "Generation=500")
+	assert.Nil(t, err)
+	assert.Equal(t, "Update complete. Matched: 5 Modified: 5 Upserted: 0\n", stdout)
+	assert.Equal(t, 5, len(findStored(t, storePath, &store.Filter{Generation: "500"})))
+}
+
+func TestUpdateCmdPatchInvalid(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Read-Only Position", []string{"--all", "--set", "Position=1"}, "Unknown or read-only field: 'Position'"},
+		{"Read-Only UniqueId", []string{"--all", "--set", "uniqueid=abc"}, "Unknown or read-only field: 'uniqueid'"},
+		{"Unknown Field", []string{"--all", "--set", "Colour=red"}, "Unknown or read-only field: 'Colour'"},
+		{"Missing Value", []string{"--all", "--set", "Generation"}, "Invalid field assignment: 'Generation'"},
+		{"Unfiltered", []string{"--set", "Generation=500"}, errUnfilteredPatchMsg},
+		{"Filtered All", []string{"--all", "--mutation-type", "snp", "--set", "Generation=500"}, errFilteredAllMsg},
+	}
+
+	for _, c := range cases {
+		storePath := uploadTestStore(t)
+		stdout, _, err := executeCommand(t, "", append([]string{"update", "--local-store", storePath}, c.args...)...)
+		assert.NotNil(t, err, c.name)
+		if err != nil {
+			assert.True(t, strings.HasPrefix(err.Error(), c.expected), c.name)
+		}
+		assert.Equal(t, exitError, commandExitCode(err), c.name)
+		assert.Equal(t, "", stdout, c.name)
+		assert.Equal(t, 5, len(findStored(t, storePath, &store.Filter{})), c.name)
+		assert.Equal(t, 0, len(findStored(t, storePath, &store.Filter{Generation: "500"})), c.name)
+	}
+}
+
+func TestUpdateCmdUpsert(t *testing.T) {
+	storePath := uploadTestStore(t)
+
+	// The re-run replaces the uploaded file, annotating the first mutation with its
+	// gene, and finding another one.
+	rerun := strings.Replace(testGdData, "SNP\t1\t.\tREL606\t1000\tA\n", "SNP\t1\t.\tREL606\t1000\tA\tgene_name=abcA\n", 1) +
+		"SNP\t6\t.\tREL606\t6000\tT\n"
+	filePath := writeTestFile(t, filepath.Dir(storePath), "output.gd", rerun)
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"Re-Run", "Matched: 5 Modified: 1 Upserted: 1"},
+		{"Same Re-Run Again", "Matched: 6 Modified: 0 Upserted: 0"},
+	}
+
+	for _, c := range cases {
+		stdout, stderr, err := executeCommand(t, "", "update", "--status", "--local-store", storePath, filePath)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, "Update complete. "+c.expected+"\n", stdout, c.name)
+		assert.Contains(t, stderr, fmt.Sprintf("Re-uploaded File: %s %s\n", filePath, c.expected), c.name)
+	}
+
+	assert.Equal(t, 6, len(findStored(t, storePath, &store.Filter{})))
+	genes := findStored(t, storePath, &store.Filter{Gene: "abcA"})
+	assert.Equal(t, 1, len(genes))
+	assert.Equal(t, "1,000", genes[0].Position)
+}
+
+func TestUpdateCmdUpsertInvalid(t *testing.T) {
+	storePath := uploadTestStore(t)
+	filePath := writeTestFile(t, filepath.Dir(storePath), "rerun.gd", testGdData)
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Filtered", []string{"--mutation-type", "snp"}, errFilteredReuploadMsg},
+		{"All", []string{"--all"}, errFilteredReuploadMsg},
+	}
+
+	for _, c := range cases {
+		_, _, err := executeCommand(t, "", append([]string{"update", "--local-store", storePath, filePath}, c.args...)...)
+		assert.NotNil(t, err, c.name)
+		if err != nil {
+			assert.Equal(t, c.expected, err.Error(), c.name)
+		}
+	}
+
+	// Missing filepaths are still a re-upload, rather than a patch.
+	missingPath := filepath.Join(filepath.Dir(storePath), "missing.gd")
+	_, _, err := executeCommand(t, "", "update", "--local-store", storePath, missingPath, "--all")
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Equal(t, errFilteredReuploadMsg, err.Error())
+	}
+
+	// And fail like files that don't parse, while the rest are still re-uploaded.
+	stdout, stderr, err := executeCommand(t, "", "update", "--local-store", storePath, missingPath, filePath)
+	assert.Equal(t, exitFileNotFound, commandExitCode(err))
+	assert.Contains(t, stderr, "Could not find the files.")
+	assert.Contains(t, stdout, "Update complete. Matched: 5 ")
+	assert.Equal(t, int64(5), countStored(t, storePath))
+
+	// Failures are reported on stderr, leaving only the summary on stdout.
+	malformedPath := writeTestFile(t, filepath.Dir(storePath), "malformed.gd", testGdHeader+"SNP\t1\t.\tREL606\n")
+	stdout, stderr, err = executeCommand(t, "", "update", "--local-store", storePath, malformedPath)
+	assert.Equal(t, exitMalformedTable, commandExitCode(err))
+	assert.Equal(t, "Update complete. Matched: 0 Modified: 0 Upserted: 0\n", stdout)
+	assert.Contains(t, stderr, "Could not parse the file.")
+
+	stdout, _, err = executeCommand(t, "", "update", "--local-store", storePath)
+	assert.Nil(t, err)
+	assert.Equal(t, errNoUpdateMsg+"\n", stdout)
+}
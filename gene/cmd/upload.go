@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/gene/cmd/parse"
+	"github.com/bio-pdv/tools/model"
+	"github.com/bio-pdv/tools/store"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	uploadCmd.Flags().StringSliceP(fPathFlag, shortFpFlag, nil, "Filename(s), directories, or glob patterns to upload. Can be repeated or comma separated. Directories are searched recursively for index.html and *.gd files.")
+	uploadCmd.Flags().StringP(fTypeFlag, shortfTypeFlag, "", "File format type of the data. Auto-detected if not given.")
+	uploadCmd.Flags().StringP(appNameFlag, shortAnFlag, "", "Application that generated the data. Auto-detected if not given.")
+	uploadCmd.Flags().StringP(appVersFlag, shortAvFlag, "", "Version of the application that generated the data. Auto-detected if not given.")
+	uploadCmd.Flags().Int(batchSizeFlag, store.DefaultBatchSize, "Number of sequence annotations inserted per database request.")
+	addSampleSheetFlag(uploadCmd)
+	addLenientFlag(uploadCmd)
+	addStoreFlags(uploadCmd)
+}
+
+var uploadCmd = &cobra.Command{
+	Use:   "upload [filepath...]",
+	Short: "Uploads sequence annotation file(s) to the database.",
+	Long: `Uploads sequence annotation file(s) to the database.
+Each file is parsed the same way as the parse command, stamped with its sample
+from the --sample-sheet if given, then its sequence annotations are inserted
+into the collection in batches as they're parsed. Annotations that
+collide with an existing record on a unique index are skipped, so a file that
+fails part way through can be uploaded again once fixed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePaths, pathErrs := seqAnnotationFilePaths(cmd, args)
+		if len(filePaths) <= 0 && len(pathErrs) <= 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "At least one filepath is required.")
+			return nil
+		}
+
+		fType, appName, appVers, err := parserFlags(cmd)
+		if err != nil {
+			return err
+		}
+		opts := parseOptions(cmd)
+
+		sheet, err := readSampleSheet(cmd)
+		if err != nil {
+			return err
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		ctx := context.Background()
+		totalInserted, totalSkipped, failures := 0, 0, &fileFailures{}
+		reportPathErrors(cmd, pathErrs, failures)
+		batchSize, _ := cmd.Flags().GetInt(batchSizeFlag)
+		for _, filePath := range filePaths {
+			stamp, err := sampleStamper(sheet, filePath)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+				failures.add(err)
+				continue
+			}
+
+			// Sequence annotations are inserted a batch at a time as they're parsed,
+			// so the whole file is never held in memory.
+			cmdLog.Printf("Uploading File: %s\n", filePath)
+			fileResult := store.InsertResult{}
+			batch := make([]model.SequenceAnnotation, 0, batchSize)
+			var insertErr error
+			insert := func() error {
+				result, err := st.Insert(ctx, batch)
+				fileResult.Inserted += result.Inserted
+				fileResult.Skipped += result.Skipped
+				batch, insertErr = make([]model.SequenceAnnotation, 0, batchSize), err
+				return err
+			}
+
+			err = parse.ParseSeqAnnotationDataFilePathStream(filePath, fType, appName, appVers, opts, func(_ int, sa model.SequenceAnnotation) error {
+				stamp(&sa)
+				batch = append(batch, sa)
+				if len(batch) >= batchSize {
+					return insert()
+				}
+				return nil
+			})
+
+			if err == nil && len(batch) > 0 {
+				insert()
+			}
+
+			totalInserted += fileResult.Inserted
+			totalSkipped += fileResult.Skipped
+			cmdLog.Printf("Uploaded File: %s Inserted: %d Skipped: %d\n", filePath, fileResult.Inserted, fileResult.Skipped)
+			if insertErr != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Could not upload the file. Filepath: '%s' Error: '%s'\n", filePath, insertErr.Error())
+				failures.add(insertErr)
+			} else if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the file. Error: '%s'\n", err.Error())
+				failures.add(err)
+			}
+		}
+
+		cmdLog.Printf("Upload complete. Files: %d Failed: %d Inserted: %d Skipped: %d\n", len(filePaths)+len(pathErrs), failures.count, totalInserted, totalSkipped)
+		return failures.err()
+	},
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/store"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testGdHeader = "#=GENOME_DIFF\t1.0\n#=PROGRAM\tbreseq 0.35.1\n"
+	// testGdData holds 5 mutations, which is two full batches of 2 and a partial one.
+	testGdData = testGdHeader +
+		"SNP\t1\t.\tREL606\t1000\tA\n" +
+		"SNP\t2\t.\tREL606\t2000\tC\n" +
+		"INS\t3\t.\tREL606\t3000\tG\n" +
+		"DEL\t4\t.\tREL606\t4000\t10\n" +
+		"SUB\t5\t.\tREL606\t5000\t2\tAT\n"
+)
+
+// countStored counts every sequence annotation in the local store.
+func countStored(t *testing.T, storePath string) int64 {
+	st, err := store.OpenLocal(storePath, store.Options{BatchSize: store.DefaultBatchSize})
+	assert.Nil(t, err)
+	defer st.Close()
+
+	count, err := st.Count(context.Background(), &store.Filter{})
+	assert.Nil(t, err)
+	return count
+}
+
+func TestUploadCmd(t *testing.T) {
+	dir := t.TempDir()
+	filePath := writeTestFile(t, dir, "output.gd", testGdData)
+	storePath := filepath.Join(dir, "local.db")
+
+	// Uploading the file again skips every sequence annotation already stored.
+	cases := []struct {
+		name     string
+		inserted int
+		skipped  int
+	}{
+		{"First Upload", 5, 0},
+		{"Second Upload", 0, 5},
+	}
+
+	for _, c := range cases {
+		_, stderr, err := executeCommand(t, "", "upload", "--status", "--batch-size", "2", "--local-store", storePath, filePath)
+		assert.Nil(t, err, c.name)
+		assert.Contains(t, stderr, fmt.Sprintf("Uploaded File: %s Inserted: %d Skipped: %d\n", filePath, c.inserted, c.skipped), c.name)
+		assert.Contains(t, stderr, fmt.Sprintf("Upload complete. Files: 1 Failed: 0 Inserted: %d Skipped: %d\n", c.inserted, c.skipped), c.name)
+		assert.Equal(t, int64(5), countStored(t, storePath), c.name)
+	}
+}
+
+func TestUploadCmdTotals(t *testing.T) {
+	dir := t.TempDir()
+	firstPath := writeTestFile(t, dir, "first.gd", testGdData)
+	secondPath := writeTestFile(t, dir, "second.gd", testGdHeader+"SNP\t1\t.\tREL606\t1000\tA\n"+"SNP\t2\t.\tREL606\t9000\tT\n")
+	storePath := filepath.Join(dir, "local.db")
+
+	// The first file was already uploaded, so only the second file's mutations are inserted.
+	_, _, err := executeCommand(t, "", "upload", "--local-store", storePath, firstPath)
+	assert.Nil(t, err)
+	_, stderr, err := executeCommand(t, "", "upload", "--status", "--batch-size", "2", "--local-store", storePath, firstPath, secondPath)
+	assert.Nil(t, err)
+	assert.Contains(t, stderr, fmt.Sprintf("Uploaded File: %s Inserted: 0 Skipped: 5\n", firstPath))
+	assert.Contains(t, stderr, fmt.Sprintf("Uploaded File: %s Inserted: 2 Skipped: 0\n", secondPath))
+	assert.Contains(t, stderr, "Upload complete. Files: 2 Failed: 0 Inserted: 2 Skipped: 5\n")
+	assert.Equal(t, int64(7), countStored(t, storePath))
+}
+
+func TestUploadCmdSharedMutation(t *testing.T) {
+	dir := t.TempDir()
+	mutation := "SNP\t1\t.\tREL606\t1000\tA\n"
+	firstPath := writeTestFile(t, dir, "first.gd", testGdHeader+mutation)
+	secondPath := writeTestFile(t, dir, "second.gd", testGdHeader+mutation)
+	storePath := filepath.Join(dir, "local.db")
+
+	// Without a sample sheet, the same mutation called in different files is from different samples.
+	_, stderr, err := executeCommand(t, "", "upload", "--status", "--local-store", storePath, firstPath, secondPath)
+	assert.Nil(t, err)
+	assert.Contains(t, stderr, fmt.Sprintf("Uploaded File: %s Inserted: 1 Skipped: 0\n", secondPath))
+	assert.Contains(t, stderr, "Upload complete. Files: 2 Failed: 0 Inserted: 2 Skipped: 0\n")
+	assert.Equal(t, int64(2), countStored(t, storePath))
+
+	// The same file given by another path is still skipped.
+	_, stderr, err = executeCommand(t, "", "upload", "--status", "--local-store", storePath, dir+string(filepath.Separator)+"."+string(filepath.Separator)+"first.gd")
+	assert.Nil(t, err)
+	assert.Contains(t, stderr, "Upload complete. Files: 1 Failed: 0 Inserted: 0 Skipped: 1\n")
+	assert.Equal(t, int64(2), countStored(t, storePath))
+}
+
+func TestUploadCmdFailures(t *testing.T) {
+	dir := t.TempDir()
+	validPath := writeTestFile(t, dir, "valid.gd", testGdData)
+	malformedPath := writeTestFile(t, dir, "malformed.gd", testGdHeader+"SNP\t1\t.\tREL606\n")
+	storePath := filepath.Join(dir, "local.db")
+
+	// The rest of the files are uploaded, then the command exits with the first failure's code.
+	stdout, stderr, err := executeCommand(t, "", "upload", "--status", "--batch-size", "2", "--local-store", storePath, malformedPath, validPath)
+	assert.NotNil(t, err)
+	assert.Equal(t, exitMalformedTable, commandExitCode(err))
+	assert.Equal(t, "", err.Error())
+	assert.Equal(t, "", stdout)
+	assert.Contains(t, stderr, "Could not parse the file.")
+	assert.Contains(t, stderr, "Upload complete. Files: 2 Failed: 1 Inserted: 5 Skipped: 0\n")
+	assert.Equal(t, int64(5), countStored(t, storePath))
+
+	// Filepaths that don't name any files fail the same, and the rest are still uploaded.
+	otherPath := writeTestFile(t, dir, "other.gd", testGdHeader+"SNP\t1\t.\tREL606\t9000\tT\n")
+	_, stderr, err = executeCommand(t, "", "upload", "--status", "--local-store", storePath, filepath.Join(dir, "missing.gd"), otherPath)
+	assert.Equal(t, exitFileNotFound, commandExitCode(err))
+	assert.Contains(t, stderr, "Could not find the files.")
+	assert.Contains(t, stderr, "Upload complete. Files: 2 Failed: 1 Inserted: 1 Skipped: 0\n")
+	assert.Equal(t, int64(6), countStored(t, storePath))
+
+	// Files missing from the sample sheet are reported on stderr too.
+	sheetPath := writeTestFile(t, dir, "samples.csv", "path,generation\nvalid.gd,500\n")
+	stdout, stderr, err = executeCommand(t, "", "upload", "--local-store", storePath, "--sample-sheet", sheetPath, otherPath)
+	assert.Equal(t, exitError, commandExitCode(err))
+	assert.Equal(t, "", stdout)
+	assert.Contains(t, stderr, "File is not listed in the sample sheet.")
+}
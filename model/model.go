@@ -1,5 +1,20 @@
 package model
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// contentIdLen is the number of bytes of the hash kept in a content id.
+	contentIdLen = 16
+	// contentIdSeparator can't appear in the hashed fields, so different
+	// fields can't hash the same by shifting text between them.
+	contentIdSeparator = "\x00"
+)
+
 // SequenceAnnotation represents a single row describing a
 // mutation of a specific nucleotide sequence. Here's an
 // example of what a row typically looks like from a version 0.27.1
@@ -13,28 +28,56 @@ package model
 // etc.
 type SequenceAnnotation struct {
 	// UniqueId is an application generated string that uniquely
-	// identifies this sequence annotation.
+	// identifies this sequence annotation. Parsed sequence annotations
+	// are given their ContentId, so re-parsing the same data yields the
+	// same UniqueId.
 	UniqueId string
 	// SequenceId is the identifier for the reference sequence
 	// with the mutation.
 	SequenceId string
 	// Position in the reference sequence of the mutation.
 	Position string
+	// PositionValue is the Position as a number, e.g. 12345 from '12,345'.
+	PositionValue int64
 	// Generation serves two functions:
 	// (1) Groups all sequence annotations by generation.
 	// (2) Acts as a timestamp to differentiate the sequence annotations
 	//     with the same sequence id and position.
 	Generation string
+	// Population is the name of the evolving population, e.g. 'Ara-1',
+	// the sequenced sample was taken from.
+	Population string
+	// Replicate distinguishes the samples sequenced from the same
+	// population at the same generation, e.g. 'A' or '2'.
+	Replicate string
+	// Treatment is the experimental condition the population evolved
+	// under, e.g. 'glucose' or '42C'.
+	Treatment string
+	// TimePoint is when the sequenced sample was taken, for experiments
+	// not measured in generations, e.g. 'day 30'.
+	TimePoint string
 	// Mutation is a description, usually of how nucleotides
 	// are added, substituted, or deleted.
 	Mutation string
+	// Variant is the Mutation decomposed into its type and parts.
+	Variant Variant
 	// Frequency is a percentage field of how often this
 	// mutation occurs.
 	Frequency string
+	// FrequencyValue is the Frequency as a fraction within [0, 1], e.g. 0.06
+	// from '6.0%'. Clonal samples have no Frequency, so their mutations are 1.
+	FrequencyValue float64
 	// Annotation is a more detailed description of the mutation.
 	Annotation string
+	// Effect is the Annotation decomposed into the mutation's effect on the gene.
+	Effect Effect
 	// Gene is a space-delimited list of genes affected by the mutation.
 	Gene string
+	// Genes are the names of the genes listed in Gene, without the
+	// arrows and brackets, e.g. [abcB abcC] from 'abcB ← / → abcC'.
+	Genes []string
+	// GeneContext is the Gene decomposed into the strands and positions of the genes.
+	GeneContext GeneContext
 	// Description is a qualitative description of the genes affected.
 	Description string
 	// Application is the name of the application this
@@ -43,4 +86,164 @@ type SequenceAnnotation struct {
 	// AppVersion is the version of the application this
 	// annotation came from.
 	AppVersion string
+	// Source is the path of the file the sequence annotation was parsed from.
+	Source string
+}
+
+// ContentId derives an id from the fields identifying the sequenced sample,
+// the mutation, and the application that called it. Without any of the sample's
+// fields, e.g. from a sample sheet, the Source file identifies the sample instead,
+// so the same mutation called in different files isn't taken for the same one.
+// The id is a hex encoded hash, so it's the same every time the same data is parsed.
+func (sa *SequenceAnnotation) ContentId() string {
+	fields := []string{
+		sa.Population,
+		sa.Replicate,
+		sa.TimePoint,
+		sa.SequenceId,
+		sa.Position,
+		sa.Mutation,
+		sa.Generation,
+		sa.Application,
+		sa.AppVersion,
+	}
+	if !sa.hasSample() && sa.Source != "" {
+		fields = append(fields, filepath.Clean(sa.Source))
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(fields, contentIdSeparator)))
+	return hex.EncodeToString(hash[:contentIdLen])
+}
+
+// hasSample reports whether any of the fields identifying the sequenced sample are set.
+func (sa *SequenceAnnotation) hasSample() bool {
+	return sa.Population != "" || sa.Replicate != "" || sa.TimePoint != "" || sa.Generation != ""
+}
+
+// MutationType classifies the kind of change a mutation makes to the sequence.
+type MutationType string
+
+const (
+	// SnpMutation is a single base substitution, e.g. 'A→G'.
+	SnpMutation MutationType = "snp"
+	// InsertionMutation adds bases, e.g. '+G' or '+36 bp'.
+	InsertionMutation MutationType = "insertion"
+	// DeletionMutation removes bases, e.g. 'Δ1,234 bp'.
+	DeletionMutation MutationType = "deletion"
+	// SubstitutionMutation replaces multiple bases, e.g. '2 bp→AT'.
+	SubstitutionMutation MutationType = "substitution"
+	// RepeatMutation changes the copies of a short tandem repeat, e.g. '(TA)6→5'.
+	RepeatMutation MutationType = "repeat"
+	// MobileElementMutation inserts a mobile element, e.g. 'IS150 (+) +4 bp'.
+	MobileElementMutation MutationType = "mobile"
+	// AmplificationMutation duplicates a region, e.g. '1,200 bp x 2'.
+	AmplificationMutation MutationType = "amplification"
+	// InversionMutation reverses a region, e.g. '500 bp inversion'.
+	InversionMutation MutationType = "inversion"
+)
+
+// MutationTypes lists every mutation type.
+var MutationTypes = []MutationType{
+	SnpMutation,
+	InsertionMutation,
+	DeletionMutation,
+	SubstitutionMutation,
+	RepeatMutation,
+	MobileElementMutation,
+	AmplificationMutation,
+	InversionMutation,
+}
+
+// Variant is a mutation decomposed into its parts. Only the parts
+// that apply to the mutation's type are set.
+type Variant struct {
+	// Type is empty when the mutation could not be classified.
+	Type MutationType
+	// RefBases are the reference bases replaced, e.g. 'A' of 'A→G'.
+	RefBases string
+	// AltBases are the bases replacing or inserted into the reference,
+	// e.g. 'G' of 'A→G' or 'GC' of '+GC'.
+	AltBases string
+	// Length is the number of bases inserted, deleted, substituted,
+	// amplified, or inverted.
+	Length int64
+	// RepeatUnit is the bases repeated in tandem, e.g. 'TA' of '(TA)6→5'.
+	RepeatUnit string
+	// RefCopies and AltCopies are the copies of a repeat or amplified region
+	// before and after the mutation, e.g. 6 and 5 of '(TA)6→5'.
+	RefCopies int
+	AltCopies int
+	// MobileElement is the name of the inserted element, e.g. 'IS150'.
+	MobileElement string
+	// Strand is the strand the mobile element inserted on, either '+' or '-'.
+	Strand string
+	// Duplication is the number of target site bases duplicated by the
+	// mobile element insertion, e.g. 4 of 'IS150 (+) +4 bp'.
+	Duplication int64
+}
+
+// EffectCategory classifies the effect a mutation has on the gene it's in, or near.
+type EffectCategory string
+
+const (
+	// MissenseEffect changes an amino acid, e.g. 'V12A (GTG→GGG)'.
+	MissenseEffect EffectCategory = "missense"
+	// SynonymousEffect changes a codon without changing its amino acid, e.g. 'V12V (GTG→GTA)'.
+	SynonymousEffect EffectCategory = "synonymous"
+	// NonsenseEffect changes an amino acid into a stop codon, e.g. 'Q12* (CAG→TAG)'.
+	NonsenseEffect EffectCategory = "nonsense"
+	// IntergenicEffect is between genes, e.g. 'intergenic (-123/+12)'.
+	IntergenicEffect EffectCategory = "intergenic"
+	// CodingIndelEffect inserts or deletes bases in a protein coding gene, e.g. 'coding (123/456 nt)'.
+	CodingIndelEffect EffectCategory = "coding"
+	// NoncodingEffect is in a gene that isn't protein coding, e.g. 'noncoding (12/76 nt)'.
+	NoncodingEffect EffectCategory = "noncoding"
+	// PseudogeneEffect is in a pseudogene, e.g. 'pseudogene (123/456 nt)'.
+	PseudogeneEffect EffectCategory = "pseudogene"
+)
+
+// Effect is an annotation decomposed into its parts. Only the parts
+// that apply to the effect's category are set.
+type Effect struct {
+	// Category is empty when the annotation could not be classified.
+	Category EffectCategory
+	// AminoAcidRef, AminoAcidPosition, and AminoAcidAlt are the amino acid
+	// change, e.g. 'V', 12, and 'A' of 'V12A'. Stop codons are '*'.
+	AminoAcidRef      string
+	AminoAcidPosition int64
+	AminoAcidAlt      string
+	// CodonRef and CodonAlt are the codon change, e.g. 'GTG' and 'GGG' of '(GTG→GGG)'.
+	CodonRef string
+	CodonAlt string
+	// GenePosition and GeneLength are the nucleotide position within the gene,
+	// and the length of the gene, e.g. 123 and 456 of '(123/456 nt)'. Mutations
+	// spanning several nucleotides, e.g. '(123-125/456 nt)', are at the first.
+	GenePosition int64
+	GeneLength   int64
+	// LeftGeneDistance and RightGeneDistance are the distances of an intergenic
+	// mutation to the genes on its left and right, e.g. -123 and +12 of '(-123/+12)'.
+	// Negative distances are upstream of the gene's start, and positive ones are
+	// downstream of its end. Zero when there's no gene on that side.
+	LeftGeneDistance  int64
+	RightGeneDistance int64
+}
+
+// GeneContext is the gene column decomposed into the genes around a mutation, and
+// their strands. Strands are '+' for genes read left to right, shown as '→', and
+// '-' for genes read right to left, shown as '←'. Only the parts that apply to
+// the kind of gene column are set.
+type GeneContext struct {
+	// Strand is the strand of the single gene a mutation is in, e.g. '+' of 'abcA →'.
+	Strand string
+	// LeftGene, LeftStrand, RightGene, and RightStrand are the genes flanking an
+	// intergenic mutation, e.g. 'abcB', '-', 'abcC', and '+' of 'abcB ← / → abcC'.
+	// The gene and strand are empty when there's no gene on that side, shown as '–'.
+	LeftGene    string
+	LeftStrand  string
+	RightGene   string
+	RightStrand string
+	// FirstGene and LastGene are the ends of the range of genes affected by a
+	// multi-gene mutation, e.g. 'abcA' and 'abcD' of '[abcA]–[abcD]'.
+	FirstGene string
+	LastGene  string
 }
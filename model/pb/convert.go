@@ -0,0 +1,173 @@
+// Package pb holds the protobuf messages of the sequence annotation model, generated
+// from model.proto, along with their conversion to and from the model's structs.
+package pb
+
+import (
+	"github.com/bio-pdv/tools/model"
+)
+
+//go:generate protoc --proto_path=../.. --go_out=../.. --go_opt=paths=source_relative model/pb/model.proto
+
+var (
+	mutationTypes = map[model.MutationType]MutationType{
+		model.SnpMutation:           MutationType_MUTATION_TYPE_SNP,
+		model.InsertionMutation:     MutationType_MUTATION_TYPE_INSERTION,
+		model.DeletionMutation:      MutationType_MUTATION_TYPE_DELETION,
+		model.SubstitutionMutation:  MutationType_MUTATION_TYPE_SUBSTITUTION,
+		model.RepeatMutation:        MutationType_MUTATION_TYPE_REPEAT,
+		model.MobileElementMutation: MutationType_MUTATION_TYPE_MOBILE,
+		model.AmplificationMutation: MutationType_MUTATION_TYPE_AMPLIFICATION,
+		model.InversionMutation:     MutationType_MUTATION_TYPE_INVERSION,
+	}
+	modelMutationTypes = invert(mutationTypes)
+
+	effectCategories = map[model.EffectCategory]EffectCategory{
+		model.MissenseEffect:    EffectCategory_EFFECT_CATEGORY_MISSENSE,
+		model.SynonymousEffect:  EffectCategory_EFFECT_CATEGORY_SYNONYMOUS,
+		model.NonsenseEffect:    EffectCategory_EFFECT_CATEGORY_NONSENSE,
+		model.IntergenicEffect:  EffectCategory_EFFECT_CATEGORY_INTERGENIC,
+		model.CodingIndelEffect: EffectCategory_EFFECT_CATEGORY_CODING,
+		model.NoncodingEffect:   EffectCategory_EFFECT_CATEGORY_NONCODING,
+		model.PseudogeneEffect:  EffectCategory_EFFECT_CATEGORY_PSEUDOGENE,
+	}
+	modelEffectCategories = invert(effectCategories)
+)
+
+func invert[K comparable, V comparable](m map[K]V) map[V]K {
+	inverted := make(map[V]K, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+	return inverted
+}
+
+// FromModel converts the sequence annotation into its protobuf message. Unclassified
+// mutation types and effect categories are UNSPECIFIED.
+func FromModel(sa model.SequenceAnnotation) *SequenceAnnotation {
+	return &SequenceAnnotation{
+		UniqueId:       sa.UniqueId,
+		SequenceId:     sa.SequenceId,
+		Position:       sa.Position,
+		PositionValue:  sa.PositionValue,
+		Generation:     sa.Generation,
+		Population:     sa.Population,
+		Replicate:      sa.Replicate,
+		Treatment:      sa.Treatment,
+		TimePoint:      sa.TimePoint,
+		Mutation:       sa.Mutation,
+		Variant:        fromModelVariant(sa.Variant),
+		Frequency:      sa.Frequency,
+		FrequencyValue: sa.FrequencyValue,
+		Annotation:     sa.Annotation,
+		Effect:         fromModelEffect(sa.Effect),
+		Gene:           sa.Gene,
+		Genes:          sa.Genes,
+		GeneContext:    fromModelGeneContext(sa.GeneContext),
+		Description:    sa.Description,
+		Application:    sa.Application,
+		AppVersion:     sa.AppVersion,
+		Source:         sa.Source,
+	}
+}
+
+func fromModelVariant(v model.Variant) *Variant {
+	return &Variant{
+		Type:          mutationTypes[v.Type],
+		RefBases:      v.RefBases,
+		AltBases:      v.AltBases,
+		Length:        v.Length,
+		RepeatUnit:    v.RepeatUnit,
+		RefCopies:     int32(v.RefCopies),
+		AltCopies:     int32(v.AltCopies),
+		MobileElement: v.MobileElement,
+		Strand:        v.Strand,
+		Duplication:   v.Duplication,
+	}
+}
+
+func fromModelEffect(e model.Effect) *Effect {
+	return &Effect{
+		Category:          effectCategories[e.Category],
+		AminoAcidRef:      e.AminoAcidRef,
+		AminoAcidPosition: e.AminoAcidPosition,
+		AminoAcidAlt:      e.AminoAcidAlt,
+		CodonRef:          e.CodonRef,
+		CodonAlt:          e.CodonAlt,
+		GenePosition:      e.GenePosition,
+		GeneLength:        e.GeneLength,
+		LeftGeneDistance:  e.LeftGeneDistance,
+		RightGeneDistance: e.RightGeneDistance,
+	}
+}
+
+func fromModelGeneContext(c model.GeneContext) *GeneContext {
+	return &GeneContext{
+		Strand:      c.Strand,
+		LeftGene:    c.LeftGene,
+		LeftStrand:  c.LeftStrand,
+		RightGene:   c.RightGene,
+		RightStrand: c.RightStrand,
+		FirstGene:   c.FirstGene,
+		LastGene:    c.LastGene,
+	}
+}
+
+// ToModel converts the protobuf message back into the sequence annotation. Missing
+// sub-messages are left as their zero values.
+func (x *SequenceAnnotation) ToModel() model.SequenceAnnotation {
+	variant, effect, context := x.GetVariant(), x.GetEffect(), x.GetGeneContext()
+	return model.SequenceAnnotation{
+		UniqueId:      x.GetUniqueId(),
+		SequenceId:    x.GetSequenceId(),
+		Position:      x.GetPosition(),
+		PositionValue: x.GetPositionValue(),
+		Generation:    x.GetGeneration(),
+		Population:    x.GetPopulation(),
+		Replicate:     x.GetReplicate(),
+		Treatment:     x.GetTreatment(),
+		TimePoint:     x.GetTimePoint(),
+		Mutation:      x.GetMutation(),
+		Variant: model.Variant{
+			Type:          modelMutationTypes[variant.GetType()],
+			RefBases:      variant.GetRefBases(),
+			AltBases:      variant.GetAltBases(),
+			Length:        variant.GetLength(),
+			RepeatUnit:    variant.GetRepeatUnit(),
+			RefCopies:     int(variant.GetRefCopies()),
+			AltCopies:     int(variant.GetAltCopies()),
+			MobileElement: variant.GetMobileElement(),
+			Strand:        variant.GetStrand(),
+			Duplication:   variant.GetDuplication(),
+		},
+		Frequency:      x.GetFrequency(),
+		FrequencyValue: x.GetFrequencyValue(),
+		Annotation:     x.GetAnnotation(),
+		Effect: model.Effect{
+			Category:          modelEffectCategories[effect.GetCategory()],
+			AminoAcidRef:      effect.GetAminoAcidRef(),
+			AminoAcidPosition: effect.GetAminoAcidPosition(),
+			AminoAcidAlt:      effect.GetAminoAcidAlt(),
+			CodonRef:          effect.GetCodonRef(),
+			CodonAlt:          effect.GetCodonAlt(),
+			GenePosition:      effect.GetGenePosition(),
+			GeneLength:        effect.GetGeneLength(),
+			LeftGeneDistance:  effect.GetLeftGeneDistance(),
+			RightGeneDistance: effect.GetRightGeneDistance(),
+		},
+		Gene:  x.GetGene(),
+		Genes: x.GetGenes(),
+		GeneContext: model.GeneContext{
+			Strand:      context.GetStrand(),
+			LeftGene:    context.GetLeftGene(),
+			LeftStrand:  context.GetLeftStrand(),
+			RightGene:   context.GetRightGene(),
+			RightStrand: context.GetRightStrand(),
+			FirstGene:   context.GetFirstGene(),
+			LastGene:    context.GetLastGene(),
+		},
+		Description: x.GetDescription(),
+		Application: x.GetApplication(),
+		AppVersion:  x.GetAppVersion(),
+		Source:      x.GetSource(),
+	}
+}
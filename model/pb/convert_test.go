@@ -0,0 +1,78 @@
+package pb
+
+import (
+	"bytes"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"testing"
+)
+
+var (
+	testSnp = model.SequenceAnnotation{
+		UniqueId:       "snp-1",
+		SequenceId:     "REL606",
+		Position:       "12,345",
+		PositionValue:  12345,
+		Generation:     "500",
+		Population:     "Ara-1",
+		Replicate:      "A",
+		Treatment:      "glucose",
+		TimePoint:      "day 75",
+		Mutation:       "T→G",
+		Variant:        model.Variant{Type: model.SnpMutation, RefBases: "T", AltBases: "G", Length: 1},
+		Frequency:      "6.0%",
+		FrequencyValue: 0.06,
+		Annotation:     "V12A (GTG→GGG)",
+		Effect: model.Effect{Category: model.MissenseEffect, AminoAcidRef: "V", AminoAcidPosition: 12,
+			AminoAcidAlt: "A", CodonRef: "GTG", CodonAlt: "GGG"},
+		Gene:        "abcA →",
+		Genes:       []string{"abcA"},
+		GeneContext: model.GeneContext{Strand: "+"},
+		Description: "hypothetical protein",
+		Application: "breseq",
+		AppVersion:  "0.35",
+		Source:      "ara-1/500/output/index.html",
+	}
+	testMobile = model.SequenceAnnotation{
+		SequenceId: "REL606",
+		Mutation:   "IS150 (-) +3 bp",
+		Variant: model.Variant{Type: model.MobileElementMutation, MobileElement: "IS150", Strand: "-",
+			Duplication: 3},
+		Annotation:  "intergenic (-123/+12)",
+		Effect:      model.Effect{Category: model.IntergenicEffect, LeftGeneDistance: -123, RightGeneDistance: 12},
+		Genes:       []string{"abcB", "abcC"},
+		GeneContext: model.GeneContext{LeftGene: "abcB", LeftStrand: "-", RightGene: "abcC", RightStrand: "+"},
+	}
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	for _, sa := range []model.SequenceAnnotation{testSnp, testMobile, {}} {
+		assert.Equal(t, sa, FromModel(sa).ToModel(), sa.Mutation)
+	}
+}
+
+func TestConvertEnums(t *testing.T) {
+	assert.Equal(t, MutationType_MUTATION_TYPE_SNP, FromModel(testSnp).GetVariant().GetType())
+	assert.Equal(t, EffectCategory_EFFECT_CATEGORY_INTERGENIC, FromModel(testMobile).GetEffect().GetCategory())
+	assert.Equal(t, MutationType_MUTATION_TYPE_UNSPECIFIED, FromModel(model.SequenceAnnotation{}).GetVariant().GetType())
+
+	// Every model type has an enum value, so none are lost converting.
+	for _, mutationType := range model.MutationTypes {
+		assert.Contains(t, mutationTypes, mutationType)
+	}
+}
+
+func TestConvertDelimited(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, sa := range []model.SequenceAnnotation{testSnp, testMobile} {
+		_, err := protodelim.MarshalTo(buf, FromModel(sa))
+		assert.Nil(t, err)
+	}
+
+	for _, expected := range []model.SequenceAnnotation{testSnp, testMobile} {
+		msg := &SequenceAnnotation{}
+		assert.Nil(t, protodelim.UnmarshalFrom(buf, msg))
+		assert.Equal(t, expected, msg.ToModel())
+	}
+}
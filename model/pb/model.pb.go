@@ -0,0 +1,864 @@
+// Protobuf schema of the sequence annotation model, so the bio-pdv service can
+// consume the parser's output without round-tripping through CSV. Mirrors the
+// structs in model.go, which remain the types the tools work with; see convert.go.
+//
+// Regenerate model.pb.go after changing this file, with protoc and protoc-gen-go
+// installed, by running: go generate ./model/pb
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: model/pb/model.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MutationType classifies the kind of change a mutation makes to the sequence.
+type MutationType int32
+
+const (
+	MutationType_MUTATION_TYPE_UNSPECIFIED   MutationType = 0
+	MutationType_MUTATION_TYPE_SNP           MutationType = 1
+	MutationType_MUTATION_TYPE_INSERTION     MutationType = 2
+	MutationType_MUTATION_TYPE_DELETION      MutationType = 3
+	MutationType_MUTATION_TYPE_SUBSTITUTION  MutationType = 4
+	MutationType_MUTATION_TYPE_REPEAT        MutationType = 5
+	MutationType_MUTATION_TYPE_MOBILE        MutationType = 6
+	MutationType_MUTATION_TYPE_AMPLIFICATION MutationType = 7
+	MutationType_MUTATION_TYPE_INVERSION     MutationType = 8
+)
+
+// Enum value maps for MutationType.
+var (
+	MutationType_name = map[int32]string{
+		0: "MUTATION_TYPE_UNSPECIFIED",
+		1: "MUTATION_TYPE_SNP",
+		2: "MUTATION_TYPE_INSERTION",
+		3: "MUTATION_TYPE_DELETION",
+		4: "MUTATION_TYPE_SUBSTITUTION",
+		5: "MUTATION_TYPE_REPEAT",
+		6: "MUTATION_TYPE_MOBILE",
+		7: "MUTATION_TYPE_AMPLIFICATION",
+		8: "MUTATION_TYPE_INVERSION",
+	}
+	MutationType_value = map[string]int32{
+		"MUTATION_TYPE_UNSPECIFIED":   0,
+		"MUTATION_TYPE_SNP":           1,
+		"MUTATION_TYPE_INSERTION":     2,
+		"MUTATION_TYPE_DELETION":      3,
+		"MUTATION_TYPE_SUBSTITUTION":  4,
+		"MUTATION_TYPE_REPEAT":        5,
+		"MUTATION_TYPE_MOBILE":        6,
+		"MUTATION_TYPE_AMPLIFICATION": 7,
+		"MUTATION_TYPE_INVERSION":     8,
+	}
+)
+
+func (x MutationType) Enum() *MutationType {
+	p := new(MutationType)
+	*p = x
+	return p
+}
+
+func (x MutationType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MutationType) Descriptor() protoreflect.EnumDescriptor {
+	return file_model_pb_model_proto_enumTypes[0].Descriptor()
+}
+
+func (MutationType) Type() protoreflect.EnumType {
+	return &file_model_pb_model_proto_enumTypes[0]
+}
+
+func (x MutationType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MutationType.Descriptor instead.
+func (MutationType) EnumDescriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{0}
+}
+
+// EffectCategory classifies the effect a mutation has on the gene it's in, or near.
+type EffectCategory int32
+
+const (
+	EffectCategory_EFFECT_CATEGORY_UNSPECIFIED EffectCategory = 0
+	EffectCategory_EFFECT_CATEGORY_MISSENSE    EffectCategory = 1
+	EffectCategory_EFFECT_CATEGORY_SYNONYMOUS  EffectCategory = 2
+	EffectCategory_EFFECT_CATEGORY_NONSENSE    EffectCategory = 3
+	EffectCategory_EFFECT_CATEGORY_INTERGENIC  EffectCategory = 4
+	EffectCategory_EFFECT_CATEGORY_CODING      EffectCategory = 5
+	EffectCategory_EFFECT_CATEGORY_NONCODING   EffectCategory = 6
+	EffectCategory_EFFECT_CATEGORY_PSEUDOGENE  EffectCategory = 7
+)
+
+// Enum value maps for EffectCategory.
+var (
+	EffectCategory_name = map[int32]string{
+		0: "EFFECT_CATEGORY_UNSPECIFIED",
+		1: "EFFECT_CATEGORY_MISSENSE",
+		2: "EFFECT_CATEGORY_SYNONYMOUS",
+		3: "EFFECT_CATEGORY_NONSENSE",
+		4: "EFFECT_CATEGORY_INTERGENIC",
+		5: "EFFECT_CATEGORY_CODING",
+		6: "EFFECT_CATEGORY_NONCODING",
+		7: "EFFECT_CATEGORY_PSEUDOGENE",
+	}
+	EffectCategory_value = map[string]int32{
+		"EFFECT_CATEGORY_UNSPECIFIED": 0,
+		"EFFECT_CATEGORY_MISSENSE":    1,
+		"EFFECT_CATEGORY_SYNONYMOUS":  2,
+		"EFFECT_CATEGORY_NONSENSE":    3,
+		"EFFECT_CATEGORY_INTERGENIC":  4,
+		"EFFECT_CATEGORY_CODING":      5,
+		"EFFECT_CATEGORY_NONCODING":   6,
+		"EFFECT_CATEGORY_PSEUDOGENE":  7,
+	}
+)
+
+func (x EffectCategory) Enum() *EffectCategory {
+	p := new(EffectCategory)
+	*p = x
+	return p
+}
+
+func (x EffectCategory) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EffectCategory) Descriptor() protoreflect.EnumDescriptor {
+	return file_model_pb_model_proto_enumTypes[1].Descriptor()
+}
+
+func (EffectCategory) Type() protoreflect.EnumType {
+	return &file_model_pb_model_proto_enumTypes[1]
+}
+
+func (x EffectCategory) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EffectCategory.Descriptor instead.
+func (EffectCategory) EnumDescriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{1}
+}
+
+// SequenceAnnotation is a single row describing a mutation of a specific
+// nucleotide sequence.
+type SequenceAnnotation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UniqueId      string                 `protobuf:"bytes,1,opt,name=unique_id,json=uniqueId,proto3" json:"unique_id,omitempty"`
+	SequenceId    string                 `protobuf:"bytes,2,opt,name=sequence_id,json=sequenceId,proto3" json:"sequence_id,omitempty"`
+	Position      string                 `protobuf:"bytes,3,opt,name=position,proto3" json:"position,omitempty"`
+	PositionValue int64                  `protobuf:"varint,4,opt,name=position_value,json=positionValue,proto3" json:"position_value,omitempty"`
+	Generation    string                 `protobuf:"bytes,5,opt,name=generation,proto3" json:"generation,omitempty"`
+	Population    string                 `protobuf:"bytes,6,opt,name=population,proto3" json:"population,omitempty"`
+	Replicate     string                 `protobuf:"bytes,7,opt,name=replicate,proto3" json:"replicate,omitempty"`
+	Treatment     string                 `protobuf:"bytes,8,opt,name=treatment,proto3" json:"treatment,omitempty"`
+	TimePoint     string                 `protobuf:"bytes,9,opt,name=time_point,json=timePoint,proto3" json:"time_point,omitempty"`
+	Mutation      string                 `protobuf:"bytes,10,opt,name=mutation,proto3" json:"mutation,omitempty"`
+	Variant       *Variant               `protobuf:"bytes,11,opt,name=variant,proto3" json:"variant,omitempty"`
+	Frequency     string                 `protobuf:"bytes,12,opt,name=frequency,proto3" json:"frequency,omitempty"`
+	// Fraction within [0, 1].
+	FrequencyValue float64      `protobuf:"fixed64,13,opt,name=frequency_value,json=frequencyValue,proto3" json:"frequency_value,omitempty"`
+	Annotation     string       `protobuf:"bytes,14,opt,name=annotation,proto3" json:"annotation,omitempty"`
+	Effect         *Effect      `protobuf:"bytes,15,opt,name=effect,proto3" json:"effect,omitempty"`
+	Gene           string       `protobuf:"bytes,16,opt,name=gene,proto3" json:"gene,omitempty"`
+	Genes          []string     `protobuf:"bytes,17,rep,name=genes,proto3" json:"genes,omitempty"`
+	GeneContext    *GeneContext `protobuf:"bytes,18,opt,name=gene_context,json=geneContext,proto3" json:"gene_context,omitempty"`
+	Description    string       `protobuf:"bytes,19,opt,name=description,proto3" json:"description,omitempty"`
+	Application    string       `protobuf:"bytes,20,opt,name=application,proto3" json:"application,omitempty"`
+	AppVersion     string       `protobuf:"bytes,21,opt,name=app_version,json=appVersion,proto3" json:"app_version,omitempty"`
+	// Path of the file the sequence annotation was parsed from.
+	Source        string `protobuf:"bytes,22,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SequenceAnnotation) Reset() {
+	*x = SequenceAnnotation{}
+	mi := &file_model_pb_model_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SequenceAnnotation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceAnnotation) ProtoMessage() {}
+
+func (x *SequenceAnnotation) ProtoReflect() protoreflect.Message {
+	mi := &file_model_pb_model_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceAnnotation.ProtoReflect.Descriptor instead.
+func (*SequenceAnnotation) Descriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SequenceAnnotation) GetUniqueId() string {
+	if x != nil {
+		return x.UniqueId
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetSequenceId() string {
+	if x != nil {
+		return x.SequenceId
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetPositionValue() int64 {
+	if x != nil {
+		return x.PositionValue
+	}
+	return 0
+}
+
+func (x *SequenceAnnotation) GetGeneration() string {
+	if x != nil {
+		return x.Generation
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetPopulation() string {
+	if x != nil {
+		return x.Population
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetReplicate() string {
+	if x != nil {
+		return x.Replicate
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetTreatment() string {
+	if x != nil {
+		return x.Treatment
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetTimePoint() string {
+	if x != nil {
+		return x.TimePoint
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetMutation() string {
+	if x != nil {
+		return x.Mutation
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetVariant() *Variant {
+	if x != nil {
+		return x.Variant
+	}
+	return nil
+}
+
+func (x *SequenceAnnotation) GetFrequency() string {
+	if x != nil {
+		return x.Frequency
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetFrequencyValue() float64 {
+	if x != nil {
+		return x.FrequencyValue
+	}
+	return 0
+}
+
+func (x *SequenceAnnotation) GetAnnotation() string {
+	if x != nil {
+		return x.Annotation
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetEffect() *Effect {
+	if x != nil {
+		return x.Effect
+	}
+	return nil
+}
+
+func (x *SequenceAnnotation) GetGene() string {
+	if x != nil {
+		return x.Gene
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetGenes() []string {
+	if x != nil {
+		return x.Genes
+	}
+	return nil
+}
+
+func (x *SequenceAnnotation) GetGeneContext() *GeneContext {
+	if x != nil {
+		return x.GeneContext
+	}
+	return nil
+}
+
+func (x *SequenceAnnotation) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetApplication() string {
+	if x != nil {
+		return x.Application
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetAppVersion() string {
+	if x != nil {
+		return x.AppVersion
+	}
+	return ""
+}
+
+func (x *SequenceAnnotation) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+// Variant is a mutation decomposed into its parts.
+type Variant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          MutationType           `protobuf:"varint,1,opt,name=type,proto3,enum=biopdv.model.MutationType" json:"type,omitempty"`
+	RefBases      string                 `protobuf:"bytes,2,opt,name=ref_bases,json=refBases,proto3" json:"ref_bases,omitempty"`
+	AltBases      string                 `protobuf:"bytes,3,opt,name=alt_bases,json=altBases,proto3" json:"alt_bases,omitempty"`
+	Length        int64                  `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"`
+	RepeatUnit    string                 `protobuf:"bytes,5,opt,name=repeat_unit,json=repeatUnit,proto3" json:"repeat_unit,omitempty"`
+	RefCopies     int32                  `protobuf:"varint,6,opt,name=ref_copies,json=refCopies,proto3" json:"ref_copies,omitempty"`
+	AltCopies     int32                  `protobuf:"varint,7,opt,name=alt_copies,json=altCopies,proto3" json:"alt_copies,omitempty"`
+	MobileElement string                 `protobuf:"bytes,8,opt,name=mobile_element,json=mobileElement,proto3" json:"mobile_element,omitempty"`
+	// Either '+' or '-'.
+	Strand        string `protobuf:"bytes,9,opt,name=strand,proto3" json:"strand,omitempty"`
+	Duplication   int64  `protobuf:"varint,10,opt,name=duplication,proto3" json:"duplication,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_model_pb_model_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_model_pb_model_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Variant) GetType() MutationType {
+	if x != nil {
+		return x.Type
+	}
+	return MutationType_MUTATION_TYPE_UNSPECIFIED
+}
+
+func (x *Variant) GetRefBases() string {
+	if x != nil {
+		return x.RefBases
+	}
+	return ""
+}
+
+func (x *Variant) GetAltBases() string {
+	if x != nil {
+		return x.AltBases
+	}
+	return ""
+}
+
+func (x *Variant) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Variant) GetRepeatUnit() string {
+	if x != nil {
+		return x.RepeatUnit
+	}
+	return ""
+}
+
+func (x *Variant) GetRefCopies() int32 {
+	if x != nil {
+		return x.RefCopies
+	}
+	return 0
+}
+
+func (x *Variant) GetAltCopies() int32 {
+	if x != nil {
+		return x.AltCopies
+	}
+	return 0
+}
+
+func (x *Variant) GetMobileElement() string {
+	if x != nil {
+		return x.MobileElement
+	}
+	return ""
+}
+
+func (x *Variant) GetStrand() string {
+	if x != nil {
+		return x.Strand
+	}
+	return ""
+}
+
+func (x *Variant) GetDuplication() int64 {
+	if x != nil {
+		return x.Duplication
+	}
+	return 0
+}
+
+// Effect is an annotation decomposed into its parts.
+type Effect struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Category          EffectCategory         `protobuf:"varint,1,opt,name=category,proto3,enum=biopdv.model.EffectCategory" json:"category,omitempty"`
+	AminoAcidRef      string                 `protobuf:"bytes,2,opt,name=amino_acid_ref,json=aminoAcidRef,proto3" json:"amino_acid_ref,omitempty"`
+	AminoAcidPosition int64                  `protobuf:"varint,3,opt,name=amino_acid_position,json=aminoAcidPosition,proto3" json:"amino_acid_position,omitempty"`
+	AminoAcidAlt      string                 `protobuf:"bytes,4,opt,name=amino_acid_alt,json=aminoAcidAlt,proto3" json:"amino_acid_alt,omitempty"`
+	CodonRef          string                 `protobuf:"bytes,5,opt,name=codon_ref,json=codonRef,proto3" json:"codon_ref,omitempty"`
+	CodonAlt          string                 `protobuf:"bytes,6,opt,name=codon_alt,json=codonAlt,proto3" json:"codon_alt,omitempty"`
+	GenePosition      int64                  `protobuf:"varint,7,opt,name=gene_position,json=genePosition,proto3" json:"gene_position,omitempty"`
+	GeneLength        int64                  `protobuf:"varint,8,opt,name=gene_length,json=geneLength,proto3" json:"gene_length,omitempty"`
+	LeftGeneDistance  int64                  `protobuf:"varint,9,opt,name=left_gene_distance,json=leftGeneDistance,proto3" json:"left_gene_distance,omitempty"`
+	RightGeneDistance int64                  `protobuf:"varint,10,opt,name=right_gene_distance,json=rightGeneDistance,proto3" json:"right_gene_distance,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Effect) Reset() {
+	*x = Effect{}
+	mi := &file_model_pb_model_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Effect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Effect) ProtoMessage() {}
+
+func (x *Effect) ProtoReflect() protoreflect.Message {
+	mi := &file_model_pb_model_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Effect.ProtoReflect.Descriptor instead.
+func (*Effect) Descriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Effect) GetCategory() EffectCategory {
+	if x != nil {
+		return x.Category
+	}
+	return EffectCategory_EFFECT_CATEGORY_UNSPECIFIED
+}
+
+func (x *Effect) GetAminoAcidRef() string {
+	if x != nil {
+		return x.AminoAcidRef
+	}
+	return ""
+}
+
+func (x *Effect) GetAminoAcidPosition() int64 {
+	if x != nil {
+		return x.AminoAcidPosition
+	}
+	return 0
+}
+
+func (x *Effect) GetAminoAcidAlt() string {
+	if x != nil {
+		return x.AminoAcidAlt
+	}
+	return ""
+}
+
+func (x *Effect) GetCodonRef() string {
+	if x != nil {
+		return x.CodonRef
+	}
+	return ""
+}
+
+func (x *Effect) GetCodonAlt() string {
+	if x != nil {
+		return x.CodonAlt
+	}
+	return ""
+}
+
+func (x *Effect) GetGenePosition() int64 {
+	if x != nil {
+		return x.GenePosition
+	}
+	return 0
+}
+
+func (x *Effect) GetGeneLength() int64 {
+	if x != nil {
+		return x.GeneLength
+	}
+	return 0
+}
+
+func (x *Effect) GetLeftGeneDistance() int64 {
+	if x != nil {
+		return x.LeftGeneDistance
+	}
+	return 0
+}
+
+func (x *Effect) GetRightGeneDistance() int64 {
+	if x != nil {
+		return x.RightGeneDistance
+	}
+	return 0
+}
+
+// GeneContext is the gene column decomposed into the genes around a mutation,
+// and their strands, either '+' or '-'.
+type GeneContext struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Strand        string                 `protobuf:"bytes,1,opt,name=strand,proto3" json:"strand,omitempty"`
+	LeftGene      string                 `protobuf:"bytes,2,opt,name=left_gene,json=leftGene,proto3" json:"left_gene,omitempty"`
+	LeftStrand    string                 `protobuf:"bytes,3,opt,name=left_strand,json=leftStrand,proto3" json:"left_strand,omitempty"`
+	RightGene     string                 `protobuf:"bytes,4,opt,name=right_gene,json=rightGene,proto3" json:"right_gene,omitempty"`
+	RightStrand   string                 `protobuf:"bytes,5,opt,name=right_strand,json=rightStrand,proto3" json:"right_strand,omitempty"`
+	FirstGene     string                 `protobuf:"bytes,6,opt,name=first_gene,json=firstGene,proto3" json:"first_gene,omitempty"`
+	LastGene      string                 `protobuf:"bytes,7,opt,name=last_gene,json=lastGene,proto3" json:"last_gene,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GeneContext) Reset() {
+	*x = GeneContext{}
+	mi := &file_model_pb_model_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GeneContext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeneContext) ProtoMessage() {}
+
+func (x *GeneContext) ProtoReflect() protoreflect.Message {
+	mi := &file_model_pb_model_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeneContext.ProtoReflect.Descriptor instead.
+func (*GeneContext) Descriptor() ([]byte, []int) {
+	return file_model_pb_model_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GeneContext) GetStrand() string {
+	if x != nil {
+		return x.Strand
+	}
+	return ""
+}
+
+func (x *GeneContext) GetLeftGene() string {
+	if x != nil {
+		return x.LeftGene
+	}
+	return ""
+}
+
+func (x *GeneContext) GetLeftStrand() string {
+	if x != nil {
+		return x.LeftStrand
+	}
+	return ""
+}
+
+func (x *GeneContext) GetRightGene() string {
+	if x != nil {
+		return x.RightGene
+	}
+	return ""
+}
+
+func (x *GeneContext) GetRightStrand() string {
+	if x != nil {
+		return x.RightStrand
+	}
+	return ""
+}
+
+func (x *GeneContext) GetFirstGene() string {
+	if x != nil {
+		return x.FirstGene
+	}
+	return ""
+}
+
+func (x *GeneContext) GetLastGene() string {
+	if x != nil {
+		return x.LastGene
+	}
+	return ""
+}
+
+var File_model_pb_model_proto protoreflect.FileDescriptor
+
+const file_model_pb_model_proto_rawDesc = "" +
+	"\n" +
+	"\x14model/pb/model.proto\x12\fbiopdv.model\"\xf7\x05\n" +
+	"\x12SequenceAnnotation\x12\x1b\n" +
+	"\tunique_id\x18\x01 \x01(\tR\buniqueId\x12\x1f\n" +
+	"\vsequence_id\x18\x02 \x01(\tR\n" +
+	"sequenceId\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\tR\bposition\x12%\n" +
+	"\x0eposition_value\x18\x04 \x01(\x03R\rpositionValue\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x05 \x01(\tR\n" +
+	"generation\x12\x1e\n" +
+	"\n" +
+	"population\x18\x06 \x01(\tR\n" +
+	"population\x12\x1c\n" +
+	"\treplicate\x18\a \x01(\tR\treplicate\x12\x1c\n" +
+	"\ttreatment\x18\b \x01(\tR\ttreatment\x12\x1d\n" +
+	"\n" +
+	"time_point\x18\t \x01(\tR\ttimePoint\x12\x1a\n" +
+	"\bmutation\x18\n" +
+	" \x01(\tR\bmutation\x12/\n" +
+	"\avariant\x18\v \x01(\v2\x15.biopdv.model.VariantR\avariant\x12\x1c\n" +
+	"\tfrequency\x18\f \x01(\tR\tfrequency\x12'\n" +
+	"\x0ffrequency_value\x18\r \x01(\x01R\x0efrequencyValue\x12\x1e\n" +
+	"\n" +
+	"annotation\x18\x0e \x01(\tR\n" +
+	"annotation\x12,\n" +
+	"\x06effect\x18\x0f \x01(\v2\x14.biopdv.model.EffectR\x06effect\x12\x12\n" +
+	"\x04gene\x18\x10 \x01(\tR\x04gene\x12\x14\n" +
+	"\x05genes\x18\x11 \x03(\tR\x05genes\x12<\n" +
+	"\fgene_context\x18\x12 \x01(\v2\x19.biopdv.model.GeneContextR\vgeneContext\x12 \n" +
+	"\vdescription\x18\x13 \x01(\tR\vdescription\x12 \n" +
+	"\vapplication\x18\x14 \x01(\tR\vapplication\x12\x1f\n" +
+	"\vapp_version\x18\x15 \x01(\tR\n" +
+	"appVersion\x12\x16\n" +
+	"\x06source\x18\x16 \x01(\tR\x06source\"\xcb\x02\n" +
+	"\aVariant\x12.\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1a.biopdv.model.MutationTypeR\x04type\x12\x1b\n" +
+	"\tref_bases\x18\x02 \x01(\tR\brefBases\x12\x1b\n" +
+	"\talt_bases\x18\x03 \x01(\tR\baltBases\x12\x16\n" +
+	"\x06length\x18\x04 \x01(\x03R\x06length\x12\x1f\n" +
+	"\vrepeat_unit\x18\x05 \x01(\tR\n" +
+	"repeatUnit\x12\x1d\n" +
+	"\n" +
+	"ref_copies\x18\x06 \x01(\x05R\trefCopies\x12\x1d\n" +
+	"\n" +
+	"alt_copies\x18\a \x01(\x05R\taltCopies\x12%\n" +
+	"\x0emobile_element\x18\b \x01(\tR\rmobileElement\x12\x16\n" +
+	"\x06strand\x18\t \x01(\tR\x06strand\x12 \n" +
+	"\vduplication\x18\n" +
+	" \x01(\x03R\vduplication\"\x9c\x03\n" +
+	"\x06Effect\x128\n" +
+	"\bcategory\x18\x01 \x01(\x0e2\x1c.biopdv.model.EffectCategoryR\bcategory\x12$\n" +
+	"\x0eamino_acid_ref\x18\x02 \x01(\tR\faminoAcidRef\x12.\n" +
+	"\x13amino_acid_position\x18\x03 \x01(\x03R\x11aminoAcidPosition\x12$\n" +
+	"\x0eamino_acid_alt\x18\x04 \x01(\tR\faminoAcidAlt\x12\x1b\n" +
+	"\tcodon_ref\x18\x05 \x01(\tR\bcodonRef\x12\x1b\n" +
+	"\tcodon_alt\x18\x06 \x01(\tR\bcodonAlt\x12#\n" +
+	"\rgene_position\x18\a \x01(\x03R\fgenePosition\x12\x1f\n" +
+	"\vgene_length\x18\b \x01(\x03R\n" +
+	"geneLength\x12,\n" +
+	"\x12left_gene_distance\x18\t \x01(\x03R\x10leftGeneDistance\x12.\n" +
+	"\x13right_gene_distance\x18\n" +
+	" \x01(\x03R\x11rightGeneDistance\"\xe1\x01\n" +
+	"\vGeneContext\x12\x16\n" +
+	"\x06strand\x18\x01 \x01(\tR\x06strand\x12\x1b\n" +
+	"\tleft_gene\x18\x02 \x01(\tR\bleftGene\x12\x1f\n" +
+	"\vleft_strand\x18\x03 \x01(\tR\n" +
+	"leftStrand\x12\x1d\n" +
+	"\n" +
+	"right_gene\x18\x04 \x01(\tR\trightGene\x12!\n" +
+	"\fright_strand\x18\x05 \x01(\tR\vrightStrand\x12\x1d\n" +
+	"\n" +
+	"first_gene\x18\x06 \x01(\tR\tfirstGene\x12\x1b\n" +
+	"\tlast_gene\x18\a \x01(\tR\blastGene*\x8f\x02\n" +
+	"\fMutationType\x12\x1d\n" +
+	"\x19MUTATION_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11MUTATION_TYPE_SNP\x10\x01\x12\x1b\n" +
+	"\x17MUTATION_TYPE_INSERTION\x10\x02\x12\x1a\n" +
+	"\x16MUTATION_TYPE_DELETION\x10\x03\x12\x1e\n" +
+	"\x1aMUTATION_TYPE_SUBSTITUTION\x10\x04\x12\x18\n" +
+	"\x14MUTATION_TYPE_REPEAT\x10\x05\x12\x18\n" +
+	"\x14MUTATION_TYPE_MOBILE\x10\x06\x12\x1f\n" +
+	"\x1bMUTATION_TYPE_AMPLIFICATION\x10\a\x12\x1b\n" +
+	"\x17MUTATION_TYPE_INVERSION\x10\b*\x88\x02\n" +
+	"\x0eEffectCategory\x12\x1f\n" +
+	"\x1bEFFECT_CATEGORY_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18EFFECT_CATEGORY_MISSENSE\x10\x01\x12\x1e\n" +
+	"\x1aEFFECT_CATEGORY_SYNONYMOUS\x10\x02\x12\x1c\n" +
+	"\x18EFFECT_CATEGORY_NONSENSE\x10\x03\x12\x1e\n" +
+	"\x1aEFFECT_CATEGORY_INTERGENIC\x10\x04\x12\x1a\n" +
+	"\x16EFFECT_CATEGORY_CODING\x10\x05\x12\x1d\n" +
+	"\x19EFFECT_CATEGORY_NONCODING\x10\x06\x12\x1e\n" +
+	"\x1aEFFECT_CATEGORY_PSEUDOGENE\x10\aB#Z!github.com/bio-pdv/tools/model/pbb\x06proto3"
+
+var (
+	file_model_pb_model_proto_rawDescOnce sync.Once
+	file_model_pb_model_proto_rawDescData []byte
+)
+
+func file_model_pb_model_proto_rawDescGZIP() []byte {
+	file_model_pb_model_proto_rawDescOnce.Do(func() {
+		file_model_pb_model_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_model_pb_model_proto_rawDesc), len(file_model_pb_model_proto_rawDesc)))
+	})
+	return file_model_pb_model_proto_rawDescData
+}
+
+var file_model_pb_model_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_model_pb_model_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_model_pb_model_proto_goTypes = []any{
+	(MutationType)(0),          // 0: biopdv.model.MutationType
+	(EffectCategory)(0),        // 1: biopdv.model.EffectCategory
+	(*SequenceAnnotation)(nil), // 2: biopdv.model.SequenceAnnotation
+	(*Variant)(nil),            // 3: biopdv.model.Variant
+	(*Effect)(nil),             // 4: biopdv.model.Effect
+	(*GeneContext)(nil),        // 5: biopdv.model.GeneContext
+}
+var file_model_pb_model_proto_depIdxs = []int32{
+	3, // 0: biopdv.model.SequenceAnnotation.variant:type_name -> biopdv.model.Variant
+	4, // 1: biopdv.model.SequenceAnnotation.effect:type_name -> biopdv.model.Effect
+	5, // 2: biopdv.model.SequenceAnnotation.gene_context:type_name -> biopdv.model.GeneContext
+	0, // 3: biopdv.model.Variant.type:type_name -> biopdv.model.MutationType
+	1, // 4: biopdv.model.Effect.category:type_name -> biopdv.model.EffectCategory
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_model_pb_model_proto_init() }
+func file_model_pb_model_proto_init() {
+	if File_model_pb_model_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_model_pb_model_proto_rawDesc), len(file_model_pb_model_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_model_pb_model_proto_goTypes,
+		DependencyIndexes: file_model_pb_model_proto_depIdxs,
+		EnumInfos:         file_model_pb_model_proto_enumTypes,
+		MessageInfos:      file_model_pb_model_proto_msgTypes,
+	}.Build()
+	File_model_pb_model_proto = out.File
+	file_model_pb_model_proto_goTypes = nil
+	file_model_pb_model_proto_depIdxs = nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"sort"
+	"strings"
+)
+
+const (
+	errUnknownMutationTypeMsgFmt = "Unknown mutation type: '%s'. Expected one of: %s"
+	errInvalidFreqRangeMsgFmt    = "Frequency range must be within [0, 1], but got: '%g'"
+	errInvalidRangeMsgFmt        = "Invalid %s range. Minimum: '%v' is greater than the maximum: '%v'"
+)
+
+// MutationTypes lists the mutation types a Filter can match in sorted order.
+func MutationTypes() []string {
+	types := make([]string, 0, len(model.MutationTypes))
+	for _, mutationType := range model.MutationTypes {
+		types = append(types, string(mutationType))
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Filter selects sequence annotations. Every non-empty field must match, where
+// empty fields and nil ranges match everything.
+type Filter struct {
+	UniqueIds   []string
+	SequenceId  string
+	MinPosition *int64
+	MaxPosition *int64
+	Gene        string
+	// MutationType matches the type of the sequence annotation's Variant.
+	MutationType model.MutationType
+	// MinFrequency and MaxFrequency are within [0, 1].
+	MinFrequency *float64
+	MaxFrequency *float64
+	Generation   string
+	Application  string
+}
+
+// Validate checks the mutation type is known and the ranges are in order.
+func (f *Filter) Validate() error {
+	if f.MutationType != "" && !containsString(MutationTypes(), string(f.MutationType)) {
+		return fmt.Errorf(errUnknownMutationTypeMsgFmt, f.MutationType, strings.Join(MutationTypes(), ", "))
+	}
+
+	if f.MinPosition != nil && f.MaxPosition != nil && *f.MinPosition > *f.MaxPosition {
+		return fmt.Errorf(errInvalidRangeMsgFmt, "position", *f.MinPosition, *f.MaxPosition)
+	}
+
+	for _, freq := range []*float64{f.MinFrequency, f.MaxFrequency} {
+		if freq != nil && (*freq < 0 || *freq > 1) {
+			return fmt.Errorf(errInvalidFreqRangeMsgFmt, *freq)
+		}
+	}
+
+	if f.MinFrequency != nil && f.MaxFrequency != nil && *f.MinFrequency > *f.MaxFrequency {
+		return fmt.Errorf(errInvalidRangeMsgFmt, "frequency", *f.MinFrequency, *f.MaxFrequency)
+	}
+	return nil
+}
+
+// IsEmpty checks whether the filter matches every sequence annotation.
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.UniqueIds) == 0 && f.SequenceId == "" && f.MinPosition == nil &&
+		f.MaxPosition == nil && f.Gene == "" && f.MutationType == "" && f.MinFrequency == nil &&
+		f.MaxFrequency == nil && f.Generation == "" && f.Application == "")
+}
+
+// Matches checks the sequence annotation against every field of the filter.
+func (f *Filter) Matches(sa model.SequenceAnnotation) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	if len(f.UniqueIds) > 0 && !containsString(f.UniqueIds, sa.UniqueId) {
+		return false
+	}
+
+	if (f.SequenceId != "" && sa.SequenceId != f.SequenceId) ||
+		(f.Generation != "" && sa.Generation != f.Generation) ||
+		(f.Application != "" && sa.Application != f.Application) {
+		return false
+	}
+
+	if f.Gene != "" && !containsString(sa.Genes, f.Gene) {
+		return false
+	}
+
+	if f.MutationType != "" && sa.Variant.Type != f.MutationType {
+		return false
+	}
+
+	return inRange(sa.PositionValue, f.MinPosition, f.MaxPosition) &&
+		inRange(sa.FrequencyValue, f.MinFrequency, f.MaxFrequency)
+}
+
+func inRange[T int64 | float64](value T, min *T, max *T) bool {
+	return (min == nil || value >= *min) && (max == nil || value <= *max)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
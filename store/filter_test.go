@@ -0,0 +1,142 @@
+package store
+
+import (
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"sort"
+	"testing"
+)
+
+var (
+	testSnp = model.SequenceAnnotation{
+		UniqueId:       "snp-1",
+		SequenceId:     "REL606",
+		Position:       "12,345",
+		PositionValue:  12345,
+		Generation:     "500",
+		Mutation:       "T→G",
+		Variant:        model.Variant{Type: model.SnpMutation, RefBases: "T", AltBases: "G", Length: 1},
+		Frequency:      "6.0%",
+		FrequencyValue: 0.06,
+		Annotation:     "V12A (GTG→GGG)",
+		Gene:           "abcA\u00A0→",
+		Genes:          []string{"abcA"},
+		Description:    "hypothetical protein",
+		Application:    "breseq",
+		AppVersion:     "0.35",
+	}
+	testDeletion = model.SequenceAnnotation{
+		UniqueId:       "del-1",
+		SequenceId:     "REL606",
+		Position:       "1,000",
+		PositionValue:  1000,
+		Generation:     "1000",
+		Mutation:       "Δ1,234 bp",
+		Variant:        model.Variant{Type: model.DeletionMutation, Length: 1234},
+		Frequency:      "",
+		FrequencyValue: 1,
+		Gene:           "[abcD]–[abcE]",
+		Genes:          []string{"abcD", "abcE"},
+		Application:    "breseq",
+		AppVersion:     "0.35",
+	}
+)
+
+func init() {
+	log.SetOutput(ioutil.Discard)
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+func float64Ptr(n float64) *float64 {
+	return &n
+}
+
+func TestFilterMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		filter   *Filter
+		expected []bool
+	}{
+		{"Nil", nil, []bool{true, true}},
+		{"Empty", &Filter{}, []bool{true, true}},
+		{"Unique Ids", &Filter{UniqueIds: []string{"del-1", "other"}}, []bool{false, true}},
+		{"Sequence Id", &Filter{SequenceId: "REL607"}, []bool{false, false}},
+		{"Generation", &Filter{Generation: "500"}, []bool{true, false}},
+		{"Application", &Filter{Application: "breseq"}, []bool{true, true}},
+		{"Gene", &Filter{Gene: "abcA"}, []bool{true, false}},
+		{"Gene In Range", &Filter{Gene: "abcD"}, []bool{false, true}},
+		{"Gene Prefix", &Filter{Gene: "abc"}, []bool{false, false}},
+		{"Mutation Type", &Filter{MutationType: "deletion"}, []bool{false, true}},
+		{"Min Position", &Filter{MinPosition: int64Ptr(1001)}, []bool{true, false}},
+		{"Position Range", &Filter{MinPosition: int64Ptr(1000), MaxPosition: int64Ptr(1000)}, []bool{false, true}},
+		{"Max Frequency", &Filter{MaxFrequency: float64Ptr(0.5)}, []bool{true, false}},
+		{"Clonal Frequency", &Filter{MinFrequency: float64Ptr(1)}, []bool{false, true}},
+		{"Every Field", &Filter{SequenceId: "REL606", Gene: "abcA", MutationType: "snp", MinFrequency: float64Ptr(0.05)}, []bool{true, false}},
+	}
+
+	for _, c := range cases {
+		if c.filter != nil {
+			assert.Nil(t, c.filter.Validate(), c.name)
+		}
+		assert.Equal(t, c.expected[0], c.filter.Matches(testSnp), c.name)
+		assert.Equal(t, c.expected[1], c.filter.Matches(testDeletion), c.name)
+	}
+}
+
+func TestFilterValidateInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *Filter
+	}{
+		{"Unknown Mutation Type", &Filter{MutationType: "transposition"}},
+		{"Position Range", &Filter{MinPosition: int64Ptr(10), MaxPosition: int64Ptr(5)}},
+		{"Frequency Above One", &Filter{MaxFrequency: float64Ptr(6)}},
+		{"Negative Frequency", &Filter{MinFrequency: float64Ptr(-0.1)}},
+		{"Frequency Range", &Filter{MinFrequency: float64Ptr(0.6), MaxFrequency: float64Ptr(0.5)}},
+	}
+
+	for _, c := range cases {
+		assert.NotNil(t, c.filter.Validate(), c.name)
+	}
+}
+
+func TestMutationTypes(t *testing.T) {
+	types := MutationTypes()
+	assert.Len(t, types, len(model.MutationTypes))
+	assert.True(t, sort.StringsAreSorted(types))
+	assert.Contains(t, types, "snp")
+}
+
+func TestParsePatch(t *testing.T) {
+	patch, err := ParsePatch([]string{"generation=500", "Description=lipoprotein, putative"})
+	assert.Nil(t, err)
+	assert.Equal(t, Patch{"Generation": "500", "Description": "lipoprotein, putative"}, patch)
+
+	sa := testSnp
+	assert.True(t, patch.Apply(&sa))
+	assert.Equal(t, "lipoprotein, putative", sa.Description)
+	assert.False(t, patch.Apply(&sa))
+
+	for _, assignment := range []string{"UniqueId=1", "Position=1", "Mutation=A→G", "Unknown=1", "=1", "Generation"} {
+		_, err = ParsePatch([]string{assignment})
+		assert.NotNil(t, err, assignment)
+	}
+}
+
+func TestPatchApplyContentId(t *testing.T) {
+	patch := Patch{"Generation": "2000"}
+	sa := testSnp
+	sa.UniqueId = sa.ContentId()
+	assert.True(t, patch.Apply(&sa))
+	assert.Equal(t, sa.ContentId(), sa.UniqueId)
+	assert.NotEqual(t, testSnp.ContentId(), sa.UniqueId)
+
+	sa = testSnp
+	assert.True(t, patch.Apply(&sa))
+	assert.Equal(t, testSnp.UniqueId, sa.UniqueId)
+}
@@ -0,0 +1,415 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	localFileMode    = 0600
+	localOpenTimeout = time.Second
+	indexSeparator   = "\x00"
+
+	errLocalOpenMsgFmt     = "Could not open the local store. Filepath: '%s' Error: '%s'"
+	errCorruptRecordMsgFmt = "Corrupt sequence annotation record: %d Error: '%s'"
+)
+
+var (
+	// recordsBucket maps each record's sequence number to its JSON encoded sequence
+	// annotation, so iterating the bucket walks the records in insertion order.
+	recordsBucket = []byte("sequence_annotations")
+	// uniqueIdsBucket and keysBucket index the records by their UniqueId and Key. Each
+	// entry is the indexed value followed by the record's sequence number, so records
+	// sharing a value are found by seeking to the value's prefix.
+	uniqueIdsBucket = []byte("unique_ids")
+	keysBucket      = []byte("keys")
+)
+
+// localStore keeps the sequence annotations in a single local bbolt file, for
+// when there's no MongoDB server to connect to.
+type localStore struct {
+	db   *bolt.DB
+	path string
+	opts Options
+}
+
+// OpenLocal opens the local store file, creating it if it doesn't exist. The file
+// is locked while open, so the caller is responsible for closing the returned store.
+func OpenLocal(path string, opts Options) (Store, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, localFileMode, &bolt.Options{Timeout: localOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf(errLocalOpenMsgFmt, path, err.Error())
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{recordsBucket, uniqueIdsBucket, keysBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf(errLocalOpenMsgFmt, path, err.Error())
+	}
+	return &localStore{db, path, opts}, nil
+}
+
+func (s *localStore) Name() string {
+	return s.path
+}
+
+func (s *localStore) Close() error {
+	return s.db.Close()
+}
+
+// Insert skips the sequence annotations with the same UniqueId as a stored one.
+func (s *localStore) Insert(ctx context.Context, sas []model.SequenceAnnotation) (InsertResult, error) {
+	result := InsertResult{}
+	for start := 0; start < len(sas); start += s.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		end := batchEnd(start, s.opts.BatchSize, len(sas))
+		batch := InsertResult{}
+		log.Printf("Inserting batch: %d-%d of %d\n", start, end, len(sas))
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for _, sa := range sas[start:end] {
+				if sa.UniqueId != "" && findIndexed(tx.Bucket(uniqueIdsBucket), uniqueIdIndexPrefix(sa.UniqueId)) != nil {
+					batch.Skipped++
+					continue
+				}
+
+				if err := insertRecord(tx, sa); err != nil {
+					return err
+				}
+				batch.Inserted++
+			}
+			return nil
+		})
+
+		if err != nil {
+			return result, err
+		}
+		result.Inserted += batch.Inserted
+		result.Skipped += batch.Skipped
+	}
+	return result, nil
+}
+
+func (s *localStore) Upsert(ctx context.Context, sas []model.SequenceAnnotation) (UpdateResult, error) {
+	result := UpdateResult{}
+	for start := 0; start < len(sas); start += s.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		end := batchEnd(start, s.opts.BatchSize, len(sas))
+		batch := UpdateResult{}
+		log.Printf("Upserting batch: %d-%d of %d\n", start, end, len(sas))
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for _, sa := range sas[start:end] {
+				seq := findIndexed(tx.Bucket(keysBucket), keyIndexPrefix(KeyOf(sa)))
+				if seq == nil {
+					batch.Upserted++
+					if err := insertRecord(tx, sa); err != nil {
+						return err
+					}
+					continue
+				}
+
+				batch.Matched++
+				old, err := getRecord(tx, seq)
+				if err != nil {
+					return err
+				}
+
+				if reflect.DeepEqual(old, sa) {
+					continue
+				}
+
+				batch.Modified++
+				if err = replaceRecord(tx, seq, old, sa); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			return result, err
+		}
+		result.Add(batch)
+	}
+	return result, nil
+}
+
+// Find pages through the records in insertion order, so the cursor is the sequence
+// number of the last record of the previous page.
+func (s *localStore) Find(ctx context.Context, filter *Filter, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf(errInvalidPageSizeMsgFmt, pageSize)
+	}
+
+	var after []byte
+	if cursor != "" {
+		lastSeq, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf(errInvalidCursorMsgFmt, cursor)
+		}
+		after = seqKey(lastSeq)
+	}
+
+	sas := []model.SequenceAnnotation{}
+	nextCursor := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(recordsBucket).Cursor()
+		k, v := c.First()
+		if after != nil {
+			k, v = c.Seek(after)
+			if k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+
+		var lastSeq []byte
+		for ; k != nil; k, v = c.Next() {
+			sa, err := decodeRecord(k, v)
+			if err != nil {
+				return err
+			}
+
+			if !filter.Matches(sa) {
+				continue
+			}
+
+			if len(sas) == pageSize {
+				nextCursor = strconv.FormatUint(binary.BigEndian.Uint64(lastSeq), 10)
+				return nil
+			}
+			sas = append(sas, sa)
+			lastSeq = k
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+	return sas, nextCursor, nil
+}
+
+func (s *localStore) Count(ctx context.Context, filter *Filter) (int64, error) {
+	seqs, err := s.findSeqs(filter)
+	return int64(len(seqs)), err
+}
+
+func (s *localStore) Delete(ctx context.Context, filter *Filter) (int64, error) {
+	deleted := int64(0)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		seqs, err := findSeqs(tx, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, seq := range seqs {
+			sa, err := getRecord(tx, seq)
+			if err != nil {
+				return err
+			}
+
+			if err = deleteRecord(tx, seq, sa); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (s *localStore) Patch(ctx context.Context, filter *Filter, patch Patch) (UpdateResult, error) {
+	result := UpdateResult{}
+	seqs, err := s.findSeqs(filter)
+	if err != nil {
+		return result, err
+	}
+
+	for start := 0; start < len(seqs); start += s.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		end := batchEnd(start, s.opts.BatchSize, len(seqs))
+		batch := UpdateResult{}
+		log.Printf("Patching batch: %d-%d of %d\n", start, end, len(seqs))
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for _, seq := range seqs[start:end] {
+				old, err := getRecord(tx, seq)
+				if err != nil {
+					return err
+				}
+
+				batch.Matched++
+				sa := old
+				if !patch.Apply(&sa) {
+					continue
+				}
+
+				batch.Modified++
+				if err = replaceRecord(tx, seq, old, sa); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			return result, err
+		}
+		result.Add(batch)
+	}
+	return result, nil
+}
+
+func (s *localStore) findSeqs(filter *Filter) ([][]byte, error) {
+	var seqs [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		seqs, err = findSeqs(tx, filter)
+		return err
+	})
+	return seqs, err
+}
+
+// findSeqs finds the sequence numbers of the records matching the filter. The
+// returned keys are copied, so they remain valid after the transaction.
+func findSeqs(tx *bolt.Tx, filter *Filter) ([][]byte, error) {
+	seqs := [][]byte{}
+	err := tx.Bucket(recordsBucket).ForEach(func(k, v []byte) error {
+		sa, err := decodeRecord(k, v)
+		if err != nil {
+			return err
+		}
+
+		if filter.Matches(sa) {
+			seqs = append(seqs, append([]byte{}, k...))
+		}
+		return nil
+	})
+	return seqs, err
+}
+
+func insertRecord(tx *bolt.Tx, sa model.SequenceAnnotation) error {
+	n, err := tx.Bucket(recordsBucket).NextSequence()
+	if err != nil {
+		return err
+	}
+	return putRecord(tx, seqKey(n), sa)
+}
+
+func replaceRecord(tx *bolt.Tx, seq []byte, old model.SequenceAnnotation, sa model.SequenceAnnotation) error {
+	if err := deleteRecord(tx, seq, old); err != nil {
+		return err
+	}
+	return putRecord(tx, seq, sa)
+}
+
+// putRecord stores the sequence annotation and its index entries.
+func putRecord(tx *bolt.Tx, seq []byte, sa model.SequenceAnnotation) error {
+	data, err := json.Marshal(sa)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Bucket(recordsBucket).Put(seq, data); err != nil {
+		return err
+	}
+
+	if err = tx.Bucket(keysBucket).Put(indexEntry(keyIndexPrefix(KeyOf(sa)), seq), nil); err != nil {
+		return err
+	}
+
+	if sa.UniqueId == "" {
+		return nil
+	}
+	return tx.Bucket(uniqueIdsBucket).Put(indexEntry(uniqueIdIndexPrefix(sa.UniqueId), seq), nil)
+}
+
+// deleteRecord removes the sequence annotation stored as seq, along with its index entries.
+func deleteRecord(tx *bolt.Tx, seq []byte, sa model.SequenceAnnotation) error {
+	if err := tx.Bucket(recordsBucket).Delete(seq); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(keysBucket).Delete(indexEntry(keyIndexPrefix(KeyOf(sa)), seq)); err != nil {
+		return err
+	}
+
+	if sa.UniqueId == "" {
+		return nil
+	}
+	return tx.Bucket(uniqueIdsBucket).Delete(indexEntry(uniqueIdIndexPrefix(sa.UniqueId), seq))
+}
+
+func getRecord(tx *bolt.Tx, seq []byte) (model.SequenceAnnotation, error) {
+	return decodeRecord(seq, tx.Bucket(recordsBucket).Get(seq))
+}
+
+func decodeRecord(seq []byte, data []byte) (model.SequenceAnnotation, error) {
+	sa := model.SequenceAnnotation{}
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return sa, fmt.Errorf(errCorruptRecordMsgFmt, binary.BigEndian.Uint64(seq), err.Error())
+	}
+	return sa, nil
+}
+
+// findIndexed returns the sequence number of the first record indexed under the prefix.
+func findIndexed(bucket *bolt.Bucket, prefix []byte) []byte {
+	k, _ := bucket.Cursor().Seek(prefix)
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return nil
+	}
+	return append([]byte{}, k[len(prefix):]...)
+}
+
+func keyIndexPrefix(key Key) []byte {
+	return []byte(strings.Join([]string{key.SequenceId, key.Position, key.Mutation, key.Generation, key.Population,
+		key.Replicate, key.TimePoint}, indexSeparator) + indexSeparator)
+}
+
+func uniqueIdIndexPrefix(uniqueId string) []byte {
+	return []byte(uniqueId + indexSeparator)
+}
+
+func indexEntry(prefix []byte, seq []byte) []byte {
+	return append(append([]byte{}, prefix...), seq...)
+}
+
+// seqKey encodes the sequence number big endian, so the keys sort in insertion order.
+func seqKey(n uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	return key
+}
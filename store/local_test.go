@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"github.com/bio-pdv/tools/model"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+// openTestLocalStore opens a local store in a temporary directory removed after the test.
+func openTestLocalStore(t *testing.T, batchSize int) Store {
+	st, err := OpenLocal(filepath.Join(t.TempDir(), "gene.db"), Options{BatchSize: batchSize})
+	assert.Nil(t, err)
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestLocalStoreInsert(t *testing.T) {
+	ctx := context.Background()
+	st := openTestLocalStore(t, 1)
+
+	result, err := st.Insert(ctx, []model.SequenceAnnotation{testSnp, testDeletion, testSnp})
+	assert.Nil(t, err)
+	assert.Equal(t, InsertResult{Inserted: 2, Skipped: 1}, result)
+
+	count, err := st.Count(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestLocalStoreFind(t *testing.T) {
+	ctx := context.Background()
+	st := openTestLocalStore(t, DefaultBatchSize)
+	sas := []model.SequenceAnnotation{}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		sa := testSnp
+		sa.UniqueId = id
+		sas = append(sas, sa)
+	}
+	_, err := st.Insert(ctx, append(sas, testDeletion))
+	assert.Nil(t, err)
+
+	filter := &Filter{MutationType: "snp"}
+	page, cursor, err := st.Find(ctx, filter, 2, "")
+	assert.Nil(t, err)
+	assert.Equal(t, sas[:2], page)
+	assert.NotEmpty(t, cursor)
+
+	page, cursor, err = st.Find(ctx, filter, 2, cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, sas[2:4], page)
+
+	page, cursor, err = st.Find(ctx, filter, 2, cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, sas[4:], page)
+	assert.Empty(t, cursor)
+
+	_, _, err = st.Find(ctx, filter, 0, "")
+	assert.NotNil(t, err)
+	_, _, err = st.Find(ctx, filter, 2, "not-a-cursor")
+	assert.NotNil(t, err)
+}
+
+func TestLocalStoreUpsert(t *testing.T) {
+	ctx := context.Background()
+	st := openTestLocalStore(t, DefaultBatchSize)
+	_, err := st.Insert(ctx, []model.SequenceAnnotation{testSnp})
+	assert.Nil(t, err)
+
+	rerun := testSnp
+	rerun.Frequency = "7.5%"
+	result, err := st.Upsert(ctx, []model.SequenceAnnotation{rerun, testDeletion})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{Matched: 1, Modified: 1, Upserted: 1}, result)
+
+	result, err = st.Upsert(ctx, []model.SequenceAnnotation{rerun})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{Matched: 1}, result)
+
+	page, _, err := st.Find(ctx, &Filter{UniqueIds: []string{testSnp.UniqueId}}, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SequenceAnnotation{rerun}, page)
+}
+
+func TestLocalStorePatchAndDelete(t *testing.T) {
+	ctx := context.Background()
+	st := openTestLocalStore(t, 1)
+	_, err := st.Insert(ctx, []model.SequenceAnnotation{testSnp, testDeletion})
+	assert.Nil(t, err)
+
+	result, err := st.Patch(ctx, &Filter{Application: "breseq"}, Patch{"Generation": "500"})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{Matched: 2, Modified: 1}, result)
+
+	// The key index follows the patched generation.
+	result, err = st.Upsert(ctx, []model.SequenceAnnotation{testDeletion})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{Upserted: 1}, result)
+
+	deleted, err := st.Delete(ctx, &Filter{Generation: "500"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	// The deleted unique ids can be inserted again.
+	inserted, err := st.Insert(ctx, []model.SequenceAnnotation{testSnp})
+	assert.Nil(t, err)
+	assert.Equal(t, InsertResult{Inserted: 1}, inserted)
+
+	count, err := st.Count(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
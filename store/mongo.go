@@ -0,0 +1,347 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+const (
+	duplicateKeyErrCode = 11000
+	documentIdKey       = "_id"
+
+	// Document keys of the sequence annotation fields, as the driver
+	// lower cases the struct field names by default.
+	uniqueIdKey     = "uniqueid"
+	sequenceIdKey   = "sequenceid"
+	positionKey     = "position"
+	positionValKey  = "positionvalue"
+	generationKey   = "generation"
+	populationKey   = "population"
+	replicateKey    = "replicate"
+	timePointKey    = "timepoint"
+	mutationKey     = "mutation"
+	variantTypeKey  = "variant.type"
+	frequencyValKey = "frequencyvalue"
+	genesKey        = "genes"
+	applicationKey  = "application"
+
+	errMongoConnectMsgFmt = "Could not connect to the database. URI: '%s' Error: '%s'"
+)
+
+// mongoStore keeps the sequence annotations in a MongoDB collection.
+type mongoStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+	opts   Options
+}
+
+// seqAnnotationDocument is a stored sequence annotation along with its document id.
+type seqAnnotationDocument struct {
+	Id                       primitive.ObjectID `bson:"_id"`
+	model.SequenceAnnotation `bson:",inline"`
+}
+
+// OpenMongo connects to the collection, and pings the server to fail early on a bad
+// connection string. The caller is responsible for closing the returned store.
+func OpenMongo(ctx context.Context, uri string, database string, collection string, opts Options) (Store, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf(errMongoConnectMsgFmt, uri, err.Error())
+	}
+
+	if err = client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf(errMongoConnectMsgFmt, uri, err.Error())
+	}
+
+	coll := client.Database(database).Collection(collection)
+	ensureUniqueIdIndex(ctx, coll)
+	return &mongoStore{client, coll, opts}, nil
+}
+
+// ensureUniqueIdIndex creates a unique index on the non-empty UniqueIds, so inserting
+// an already uploaded sequence annotation again skips it. A collection that already
+// holds duplicates can't be indexed, so a failure is logged instead of returned.
+func ensureUniqueIdIndex(ctx context.Context, coll *mongo.Collection) {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: uniqueIdKey, Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{uniqueIdKey: bson.M{"$gt": ""}}),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, index); err != nil {
+		log.Printf("Could not create the unique id index. Error: '%s'\n", err.Error())
+	}
+}
+
+func (s *mongoStore) Name() string {
+	return s.coll.Database().Name() + "." + s.coll.Name()
+}
+
+func (s *mongoStore) Close() error {
+	return s.client.Disconnect(context.Background())
+}
+
+// Insert inserts unordered in batches, so a document that collides with an existing
+// one on a unique index is skipped rather than failing its batch.
+func (s *mongoStore) Insert(ctx context.Context, sas []model.SequenceAnnotation) (InsertResult, error) {
+	result := InsertResult{}
+	for start := 0; start < len(sas); start += s.opts.BatchSize {
+		end := batchEnd(start, s.opts.BatchSize, len(sas))
+		docs := make([]interface{}, 0, end-start)
+		for _, sa := range sas[start:end] {
+			docs = append(docs, sa)
+		}
+
+		log.Printf("Inserting batch: %d-%d of %d\n", start, end, len(sas))
+		_, err := s.coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		inserted, skipped, err := countInsertErrors(len(docs), err)
+		result.Inserted += inserted
+		result.Skipped += skipped
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// countInsertErrors splits a batch's documents into the inserted and the skipped
+// duplicates. Returns the insert error if any failure is not a duplicate key.
+func countInsertErrors(batchLen int, err error) (int, int, error) {
+	if err == nil {
+		return batchLen, 0, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return 0, 0, err
+	}
+
+	duplicates := 0
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code == duplicateKeyErrCode {
+			duplicates++
+		}
+	}
+
+	inserted := batchLen - len(bulkErr.WriteErrors)
+	if duplicates != len(bulkErr.WriteErrors) {
+		return inserted, duplicates, err
+	}
+	return inserted, duplicates, nil
+}
+
+func (s *mongoStore) Upsert(ctx context.Context, sas []model.SequenceAnnotation) (UpdateResult, error) {
+	result := UpdateResult{}
+	for start := 0; start < len(sas); start += s.opts.BatchSize {
+		end := batchEnd(start, s.opts.BatchSize, len(sas))
+		models := make([]mongo.WriteModel, 0, end-start)
+		for _, sa := range sas[start:end] {
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(mongoKeyFilter(KeyOf(sa))).SetReplacement(sa).SetUpsert(true))
+		}
+
+		log.Printf("Upserting batch: %d-%d of %d\n", start, end, len(sas))
+		bulkResult, err := s.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if bulkResult != nil {
+			result.Add(UpdateResult{bulkResult.MatchedCount, bulkResult.ModifiedCount, bulkResult.UpsertedCount})
+		}
+
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func mongoKeyFilter(key Key) bson.M {
+	return bson.M{
+		sequenceIdKey: key.SequenceId,
+		positionKey:   key.Position,
+		mutationKey:   key.Mutation,
+		generationKey: key.Generation,
+		populationKey: key.Population,
+		replicateKey:  key.Replicate,
+		timePointKey:  key.TimePoint,
+	}
+}
+
+// Find pages through the documents in id order, so the cursor is the last document
+// id of the previous page.
+func (s *mongoStore) Find(ctx context.Context, filter *Filter, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf(errInvalidPageSizeMsgFmt, pageSize)
+	}
+
+	query := mongoFilter(filter)
+	if cursor != "" {
+		lastId, err := primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf(errInvalidCursorMsgFmt, cursor)
+		}
+		query = afterDocumentId(query, lastId)
+	}
+
+	// Fetch an extra document to tell whether there's another page.
+	opts := options.Find().SetSort(bson.D{{Key: documentIdKey, Value: 1}}).SetLimit(int64(pageSize) + 1)
+	mongoCursor, err := s.coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	docs := []seqAnnotationDocument{}
+	if err = mongoCursor.All(ctx, &docs); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(docs) > pageSize {
+		docs = docs[:pageSize]
+		nextCursor = docs[pageSize-1].Id.Hex()
+	}
+
+	sas := make([]model.SequenceAnnotation, len(docs))
+	for i, doc := range docs {
+		sas[i] = doc.SequenceAnnotation
+	}
+	return sas, nextCursor, nil
+}
+
+func afterDocumentId(query bson.M, lastId primitive.ObjectID) bson.M {
+	return bson.M{"$and": bson.A{query, bson.M{documentIdKey: bson.M{"$gt": lastId}}}}
+}
+
+func (s *mongoStore) Count(ctx context.Context, filter *Filter) (int64, error) {
+	return s.coll.CountDocuments(ctx, mongoFilter(filter))
+}
+
+func (s *mongoStore) Delete(ctx context.Context, filter *Filter) (int64, error) {
+	result, err := s.coll.DeleteMany(ctx, mongoFilter(filter))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Patch updates the matches in batches, walking them in document id order, so a patch
+// changing the filtered fields won't revisit documents. The patch is applied to each
+// document before replacing it, so content derived UniqueIds are re-derived.
+func (s *mongoStore) Patch(ctx context.Context, filter *Filter, patch Patch) (UpdateResult, error) {
+	result := UpdateResult{}
+	query := mongoFilter(filter)
+	batchQuery := query
+	opts := options.Find().SetSort(bson.D{{Key: documentIdKey, Value: 1}}).SetLimit(int64(s.opts.BatchSize))
+	for {
+		mongoCursor, err := s.coll.Find(ctx, batchQuery, opts)
+		if err != nil {
+			return result, err
+		}
+
+		docs := []seqAnnotationDocument{}
+		if err = mongoCursor.All(ctx, &docs); err != nil {
+			return result, err
+		}
+
+		if len(docs) == 0 {
+			return result, nil
+		}
+
+		result.Matched += int64(len(docs))
+		models := []mongo.WriteModel{}
+		for _, doc := range docs {
+			if patch.Apply(&doc.SequenceAnnotation) {
+				models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{documentIdKey: doc.Id}).SetReplacement(doc))
+			}
+		}
+
+		if len(models) > 0 {
+			log.Printf("Patching batch of %d\n", len(models))
+			bulkResult, err := s.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+			if bulkResult != nil {
+				result.Modified += bulkResult.ModifiedCount
+			}
+
+			if err != nil {
+				return result, err
+			}
+		}
+		batchQuery = afterDocumentId(query, docs[len(docs)-1].Id)
+	}
+}
+
+// mongoFilter converts the filter into a MongoDB query document.
+func mongoFilter(f *Filter) bson.M {
+	query := bson.M{}
+	if f.IsEmpty() {
+		return query
+	}
+
+	if len(f.UniqueIds) > 0 {
+		query[uniqueIdKey] = bson.M{"$in": f.UniqueIds}
+	}
+
+	if f.SequenceId != "" {
+		query[sequenceIdKey] = f.SequenceId
+	}
+
+	if f.Generation != "" {
+		query[generationKey] = f.Generation
+	}
+
+	if f.Application != "" {
+		query[applicationKey] = f.Application
+	}
+
+	if f.Gene != "" {
+		query[genesKey] = f.Gene
+	}
+
+	if f.MutationType != "" {
+		query[variantTypeKey] = f.MutationType
+	}
+
+	if positionRange := mongoRange(f.MinPosition, f.MaxPosition); positionRange != nil {
+		query[positionValKey] = positionRange
+	}
+
+	if frequencyRange := mongoRange(f.MinFrequency, f.MaxFrequency); frequencyRange != nil {
+		query[frequencyValKey] = frequencyRange
+	}
+	return query
+}
+
+// mongoRange bounds a field by the minimum and maximum, if given.
+func mongoRange[T int64 | float64](min *T, max *T) bson.M {
+	if min == nil && max == nil {
+		return nil
+	}
+
+	bounds := bson.M{}
+	if min != nil {
+		bounds["$gte"] = *min
+	}
+
+	if max != nil {
+		bounds["$lte"] = *max
+	}
+	return bounds
+}
+
+func batchEnd(start int, batchSize int, total int) int {
+	end := start + batchSize
+	if end > total {
+		end = total
+	}
+	return end
+}
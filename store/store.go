@@ -0,0 +1,174 @@
+// Package store persists sequence annotations, either in a MongoDB collection
+// shared by the bio-pdv service, or in a single local file for working offline.
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/bio-pdv/tools/model"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultBatchSize is the number of sequence annotations written per request.
+	DefaultBatchSize = 1000
+
+	patchSeparator = "="
+
+	errInvalidBatchSizeMsgFmt  = "Batch size must be greater than 0, but got: %d"
+	errInvalidPageSizeMsgFmt   = "Page size must be greater than 0, but got: %d"
+	errInvalidCursorMsgFmt     = "Invalid cursor: '%s'. Expected the cursor printed by the previous page."
+	errInvalidPatchMsgFmt      = "Invalid field assignment: '%s'. Expected 'Field=value'."
+	errUnknownPatchFieldMsgFmt = "Unknown or read-only field: '%s'. Expected one of: %s"
+)
+
+// Store inserts, upserts, queries, and deletes sequence annotations.
+type Store interface {
+	// Name describes where the sequence annotations are stored.
+	Name() string
+	// Insert adds the sequence annotations. Annotations that collide with a stored
+	// one on a unique index are skipped. Returns the counts up to the first error.
+	Insert(ctx context.Context, sas []model.SequenceAnnotation) (InsertResult, error)
+	// Upsert replaces the stored version of each sequence annotation, matched by its
+	// Key, or inserts it if there's none. Returns the counts up to the first error.
+	Upsert(ctx context.Context, sas []model.SequenceAnnotation) (UpdateResult, error)
+	// Find returns a page of at most pageSize sequence annotations matching the filter,
+	// in insertion order, along with the cursor of the next page. The cursor is empty
+	// for the first page, and for the next page after the last one.
+	Find(ctx context.Context, filter *Filter, pageSize int, cursor string) ([]model.SequenceAnnotation, string, error)
+	// Count counts the sequence annotations matching the filter.
+	Count(ctx context.Context, filter *Filter) (int64, error)
+	// Delete deletes the sequence annotations matching the filter. Returns the number deleted.
+	Delete(ctx context.Context, filter *Filter) (int64, error)
+	// Patch sets the patched fields on the sequence annotations matching the filter.
+	// Returns the counts up to the first error.
+	Patch(ctx context.Context, filter *Filter, patch Patch) (UpdateResult, error)
+	// Close releases the connection or file.
+	Close() error
+}
+
+// Options configures how a store writes sequence annotations.
+type Options struct {
+	// BatchSize is the number of sequence annotations written per request.
+	BatchSize int
+}
+
+func (o Options) validate() error {
+	if o.BatchSize <= 0 {
+		return fmt.Errorf(errInvalidBatchSizeMsgFmt, o.BatchSize)
+	}
+	return nil
+}
+
+// InsertResult tallies the inserted sequence annotations, and those skipped as duplicates.
+type InsertResult struct {
+	Inserted int
+	Skipped  int
+}
+
+// UpdateResult tallies the sequence annotations matched, modified, and upserted by updates.
+type UpdateResult struct {
+	Matched  int64
+	Modified int64
+	Upserted int64
+}
+
+// Add sums up the counts of another update.
+func (r *UpdateResult) Add(other UpdateResult) {
+	r.Matched += other.Matched
+	r.Modified += other.Modified
+	r.Upserted += other.Upserted
+}
+
+// Key is the identity of a sequence annotation used by Upsert, which stays the same
+// when the sequencing data is re-run through the application.
+type Key struct {
+	SequenceId string
+	Position   string
+	Mutation   string
+	Generation string
+	Population string
+	Replicate  string
+	TimePoint  string
+}
+
+// KeyOf returns the Key of the sequence annotation.
+func KeyOf(sa model.SequenceAnnotation) Key {
+	return Key{sa.SequenceId, sa.Position, sa.Mutation, sa.Generation, sa.Population, sa.Replicate, sa.TimePoint}
+}
+
+// Patch maps the names of sequence annotation fields to their new values.
+type Patch map[string]string
+
+var (
+	// readOnlyFields can't be patched, either because they identify the sequence
+	// annotation, or because their typed companions would be left out of date.
+	readOnlyFields = map[string]bool{"UniqueId": true, "Position": true, "Frequency": true, "Gene": true, "Mutation": true,
+		"Annotation": true}
+)
+
+// ParsePatch parses 'Field=value' assignments into a patch. Field names are case
+// insensitive, and any string field of the sequence annotation that isn't read-only can be set.
+func ParsePatch(assignments []string) (Patch, error) {
+	fields := patchableFields()
+	patch := Patch{}
+	for _, assignment := range assignments {
+		sepIdx := strings.Index(assignment, patchSeparator)
+		if sepIdx <= 0 {
+			return nil, fmt.Errorf(errInvalidPatchMsgFmt, assignment)
+		}
+
+		name := strings.TrimSpace(assignment[:sepIdx])
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf(errUnknownPatchFieldMsgFmt, name, strings.Join(sortedFieldNames(fields), ", "))
+		}
+		patch[field] = assignment[sepIdx+1:]
+	}
+	return patch, nil
+}
+
+// patchableFields maps the lower cased names of the patchable fields to their names.
+func patchableFields() map[string]string {
+	fields := map[string]string{}
+	saType := reflect.TypeOf(model.SequenceAnnotation{})
+	for i := 0; i < saType.NumField(); i++ {
+		field := saType.Field(i)
+		if field.Type.Kind() == reflect.String && !readOnlyFields[field.Name] {
+			fields[strings.ToLower(field.Name)] = field.Name
+		}
+	}
+	return fields
+}
+
+func sortedFieldNames(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for _, name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply sets the patched fields on the sequence annotation. A UniqueId that was the
+// sequence annotation's ContentId is re-derived from the patched fields, so it stays
+// the same as re-parsing the patched data. Returns whether any changed.
+func (p Patch) Apply(sa *model.SequenceAnnotation) bool {
+	hasContentId := sa.UniqueId != "" && sa.UniqueId == sa.ContentId()
+	modified := false
+	saValue := reflect.ValueOf(sa).Elem()
+	for field, value := range p {
+		fieldValue := saValue.FieldByName(field)
+		if fieldValue.String() != value {
+			fieldValue.SetString(value)
+			modified = true
+		}
+	}
+
+	if modified && hasContentId {
+		sa.UniqueId = sa.ContentId()
+	}
+	return modified
+}